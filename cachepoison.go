@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// cachePoisonFinding is one unkeyed header whose effect was reflected in a response
+type cachePoisonFinding struct {
+	header      string // Unkeyed header that was sent
+	value       string // Value sent in that header
+	cacheBuster string // Query parameter used to isolate the test from real users, e.g. "hxcb=1a2b3c"
+	url         string // URL (including cache buster) to reproduce with
+	evidence    string // How the header's effect showed up
+	persisted   bool   // The effect was still served to a clean follow-up request (i.e. cached)
+}
+
+// cachePoisonResult holds the outcome of the cache poisoning check for a target
+type cachePoisonResult struct {
+	target        string
+	cacheDetected bool
+	cacheEvidence []string // Headers that revealed a cache
+	findings      []cachePoisonFinding
+	err           error
+}
+
+// cacheIndicatorHeaders reveal a cache between us and the origin
+var cacheIndicatorHeaders = []string{
+	"Age", "X-Cache", "X-Cache-Hits", "CF-Cache-Status", "Via", "X-Varnish",
+	"X-Served-By", "Akamai-Cache-Status", "X-Proxy-Cache", "Cache-Status", "X-Drupal-Cache",
+}
+
+// unkeyedHeaderTest describes one commonly unkeyed header and how to spot its effect
+type unkeyedHeaderTest struct {
+	header string
+	value  func(canary string) string
+	// statusChange means the header's effect is a different status/redirect rather than a
+	// reflected value (e.g. X-Forwarded-Scheme forcing an https redirect)
+	statusChange bool
+}
+
+var unkeyedHeaderTests = []unkeyedHeaderTest{
+	{header: "X-Forwarded-Host", value: func(c string) string { return c + ".hxcache.invalid" }},
+	{header: "X-Host", value: func(c string) string { return c + ".hxcache.invalid" }},
+	{header: "X-Forwarded-Server", value: func(c string) string { return c + ".hxcache.invalid" }},
+	{header: "X-Forwarded-Scheme", value: func(string) string { return "http" }, statusChange: true},
+	{header: "X-Original-URL", value: func(c string) string { return "/hxcache-" + c }, statusChange: true},
+	{header: "X-Rewrite-URL", value: func(c string) string { return "/hxcache-" + c }, statusChange: true},
+}
+
+// checkCachePoisoning looks for a cache in front of the target and, if one is present,
+// sends each unkeyed header with its own cache buster. Any effect that is still visible
+// in a clean request for the same busted URL means the poisoned response was cached.
+// The cache buster keeps every test on a URL no real user will request.
+func checkCachePoisoning(target string, mainResp *probeResponse, client *http.Client) cachePoisonResult {
+	result := cachePoisonResult{target: target}
+
+	for _, h := range cacheIndicatorHeaders {
+		if v := mainResp.header.Get(h); v != "" {
+			result.cacheEvidence = append(result.cacheEvidence, h+": "+v)
+		}
+	}
+	result.cacheDetected = len(result.cacheEvidence) > 0
+	if !result.cacheDetected {
+		return result // No cache, nothing to poison
+	}
+
+	// --- Clean baseline ---
+	// The cache buster alone may change the answer (some apps 400 or 404 on unknown
+	// parameters), so status changes are compared against an unpoisoned busted request
+	// rather than the primary probe. It gets its own buster value so it can't prime the
+	// cache entry a poison attempt is about to use.
+	baselineURL := cacheBustedURL(mainResp, "hxcb="+randomToken(6))
+	baseline, err := fetchForCacheTest(client, baselineURL, "", "")
+	if err != nil {
+		result.err = fmt.Errorf("cache poisoning baseline request failed for %s: %w", baselineURL, err)
+		return result
+	}
+
+	for _, test := range unkeyedHeaderTests {
+		canary := randomToken(6)
+		buster := "hxcb=" + randomToken(6)
+		value := test.value(canary)
+		busted := cacheBustedURL(mainResp, buster)
+
+		// --- Poison attempt ---
+		poisoned, err := fetchForCacheTest(client, busted, test.header, value)
+		if err != nil {
+			result.err = fmt.Errorf("cache poisoning request failed for %s: %w", busted, err)
+			continue
+		}
+		evidence := cacheEffect(poisoned, canary, test.statusChange, baseline.statusCode)
+		if evidence == "" {
+			continue
+		}
+
+		// --- Clean request: is the effect now served from cache? ---
+		finding := cachePoisonFinding{
+			header:      test.header,
+			value:       value,
+			cacheBuster: buster,
+			url:         busted,
+			evidence:    evidence,
+		}
+		if clean, err := fetchForCacheTest(client, busted, "", ""); err == nil {
+			if cleanEvidence := cacheEffect(clean, canary, test.statusChange, baseline.statusCode); cleanEvidence != "" {
+				finding.persisted = true
+				finding.evidence += "; clean request: " + cleanEvidence
+			}
+		}
+		result.findings = append(result.findings, finding)
+	}
+	return result
+}
+
+// cacheBustedURL adds a cache-buster parameter to the primary probe's final URL
+func cacheBustedURL(mainResp *probeResponse, buster string) string {
+	u := *mainResp.url
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	if u.RawQuery != "" {
+		u.RawQuery += "&" + buster
+	} else {
+		u.RawQuery = buster
+	}
+	return u.String()
+}
+
+// cacheTestResponse is the part of a response cacheEffect needs
+type cacheTestResponse struct {
+	statusCode int
+	header     http.Header
+	body       string
+}
+
+// fetchForCacheTest GETs a URL, optionally with one extra header
+func fetchForCacheTest(client *http.Client, target, header, value string) (*cacheTestResponse, error) {
+	req, err := http.NewRequest("GET", target, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "HyperScanner/1.4+CacheCheck")
+	if header != "" {
+		req.Header.Set(header, value)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	return &cacheTestResponse{statusCode: resp.StatusCode, header: resp.Header, body: string(body)}, nil
+}
+
+// cacheEffect describes how the canary (or a status change from the clean baseline)
+// shows up in a response
+func cacheEffect(resp *cacheTestResponse, canary string, statusChange bool, baselineStatus int) string {
+	if strings.Contains(resp.body, canary) {
+		return "canary reflected in body"
+	}
+	for name, values := range resp.header {
+		for _, v := range values {
+			if strings.Contains(v, canary) {
+				return "canary reflected in " + name + " header"
+			}
+		}
+	}
+	if statusChange && resp.statusCode != baselineStatus {
+		if loc := resp.header.Get("Location"); loc != "" {
+			return fmt.Sprintf("status %d -> %d (Location: %s)", baselineStatus, resp.statusCode, loc)
+		}
+		return fmt.Sprintf("status %d -> %d", baselineStatus, resp.statusCode)
+	}
+	return ""
+}
@@ -3,8 +3,6 @@ package main
 import (
 	"fmt"
 	"net/http"
-	"net/url"
-	"strings"
 	"time"
 )
 
@@ -18,24 +16,20 @@ type corsCheckResult struct {
 
 // checkCORS performs the actual CORS vulnerability check
 // It sends an OPTIONS request with a potentially malicious Origin.
-func checkCORS(target string, client *http.Client) corsCheckResult {
+// reqCfg's headers and cookie are sent too, so authenticated endpoints can be checked;
+// its method and body are not, since this is always an OPTIONS preflight.
+func checkCORS(target string, client *http.Client, reqCfg *requestConfig) corsCheckResult {
 	result := corsCheckResult{target: target}
 	// Use a distinct origin for testing that's unlikely to be whitelisted by chance
 	checkOrigin := "https://evil-cors-test.com"
 
-	// Ensure the target has a scheme (http/https)
-	urlToScan := target
-	if !strings.Contains(target, "://") {
-		urlToScan = "http://" + target // Default to http if no scheme
-	}
-
-	// Validate the URL structure again before the OPTIONS request
-	parsedURL, err := url.ParseRequestURI(urlToScan)
+	// Parse the target exactly as the primary request did (target.go)
+	parsed, err := ParseTarget(target)
 	if err != nil {
-		result.err = fmt.Errorf("invalid target format for CORS check '%s': %w", target, err)
+		result.err = fmt.Errorf("CORS check: %w", err)
 		return result
 	}
-	urlToScan = parsedURL.String()
+	urlToScan := parsed.String()
 
 	// --- Create OPTIONS request ---
 	req, err := http.NewRequest("OPTIONS", urlToScan, nil)
@@ -45,8 +39,8 @@ func checkCORS(target string, client *http.Client) corsCheckResult {
 	}
 
 	// --- Set Headers for CORS Check ---
-	// Use a specific user agent for CORS checks
-	req.Header.Set("User-Agent", "HyperScanner/1.4+CORSCheck")
+	// Use a specific user agent for CORS checks (unless -ua overrides it)
+	reqCfg.apply(req, "HyperScanner/1.4+CORSCheck")
 	req.Header.Set("Origin", checkOrigin)
 	// Common methods often allowed via CORS
 	req.Header.Set("Access-Control-Request-Method", "GET")
@@ -72,6 +66,13 @@ func checkCORS(target string, client *http.Client) corsCheckResult {
 		},
 	}
 
+	// Reuse the shared transport's dialer so custom resolvers apply here too
+	if shared, ok := baseTransport(client); ok && shared.DialContext != nil {
+		corsClient.Transport.(*http.Transport).DialContext = shared.DialContext
+	}
+	// ...and so does -scope (scope.go)
+	corsClient.Transport = withClientScope(client, corsClient.Transport)
+
 	// --- Perform the OPTIONS request ---
 	resp, err := corsClient.Do(req)
 	if err != nil {
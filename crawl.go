@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/html"
+)
+
+// crawlLinkAttrs maps the elements the crawler follows to the attribute holding the URL
+var crawlLinkAttrs = map[string]string{
+	"a":      "href",
+	"area":   "href",
+	"link":   "href",
+	"form":   "action",
+	"script": "src",
+	"iframe": "src",
+	"frame":  "src",
+}
+
+// extractLinks tokenizes an HTML page and returns the absolute http(s) URLs it links
+// to, resolved against the page URL (or its <base href>), without fragments and deduplicated
+func extractLinks(body []byte, pageURL *url.URL) []string {
+	base := pageURL
+	var links []string
+	seen := make(map[string]bool)
+	z := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			return links // io.EOF or a malformed page; either way we're done
+		}
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			continue
+		}
+		tok := z.Token()
+		if tok.Data == "base" {
+			if href := tokenAttr(tok, "href"); href != "" {
+				if ref, err := url.Parse(href); err == nil {
+					base = pageURL.ResolveReference(ref)
+				}
+			}
+			continue
+		}
+		attr, ok := crawlLinkAttrs[tok.Data]
+		if !ok {
+			continue
+		}
+		raw := strings.TrimSpace(tokenAttr(tok, attr))
+		if raw == "" || strings.HasPrefix(raw, "#") {
+			continue
+		}
+		ref, err := url.Parse(raw)
+		if err != nil {
+			continue
+		}
+		u := base.ResolveReference(ref)
+		if u.Scheme != "http" && u.Scheme != "https" {
+			continue // javascript:, mailto:, data:, tel: ...
+		}
+		u.Fragment = ""
+		if s := u.String(); !seen[s] {
+			seen[s] = true
+			links = append(links, s)
+		}
+	}
+}
+
+// tokenAttr returns an attribute value of an HTML token ("" if absent)
+func tokenAttr(tok html.Token, name string) string {
+	for _, a := range tok.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// sameOrigin reports whether two URLs share scheme, host and port
+func sameOrigin(a, b *url.URL) bool {
+	return a.Scheme == b.Scheme && strings.EqualFold(a.Host, b.Host)
+}
+
+// --- Per-host crawl budget (shared by all phases) ---
+var crawlPageCounts = make(map[string]int)
+var crawlPageCountsMutex sync.Mutex
+
+// reserveCrawlSlot takes one page from a host's -crawl-max budget, if any is left
+func reserveCrawlSlot(host string, max int) bool {
+	crawlPageCountsMutex.Lock()
+	defer crawlPageCountsMutex.Unlock()
+	if crawlPageCounts[host] >= max {
+		return false
+	}
+	crawlPageCounts[host]++
+	return true
+}
+
+// releaseCrawlSlot returns a slot that wasn't used (the URL turned out to be a duplicate)
+func releaseCrawlSlot(host string) {
+	crawlPageCountsMutex.Lock()
+	crawlPageCounts[host]--
+	crawlPageCountsMutex.Unlock()
+}
+
+// crawlPage queues the same-origin links of a live HTML page (and the target of a
+// same-origin redirect) as new jobs, honouring -crawl-max and, with -robots, robots.txt.
+// It returns how many URLs were queued.
+func crawlPage(resp *probeResponse, job scanJob, client *http.Client, queue *jobQueue, opts *scanOptions) int {
+	var links []string
+	if loc := resp.header.Get("Location"); loc != "" && resp.statusCode >= 300 && resp.statusCode < 400 {
+		if ref, err := url.Parse(loc); err == nil {
+			links = append(links, resp.url.ResolveReference(ref).String())
+		}
+	}
+	if looksLikeHTML(resp) { // From jsdiscovery.go
+		links = append(links, extractLinks(resp.body, resp.url)...)
+	}
+
+	var robots *robotsTxt
+	if opts.crawlRobots && len(links) > 0 {
+		robots = robotsFor(resp.url, client, opts.request) // From robots.go
+	}
+
+	queued := 0
+	for _, link := range links {
+		u, err := url.Parse(link)
+		if err != nil || !sameOrigin(u, resp.url) {
+			continue
+		}
+		if ext := strings.ToLower(path.Ext(u.Path)); ext != ".js" && jsStaticExtensions[ext] {
+			continue // Images, fonts and stylesheets tell us nothing a status code would
+		}
+		if robots != nil && !robots.allowed(u.RequestURI()) {
+			continue
+		}
+		if !reserveCrawlSlot(u.Host, opts.crawlMax) {
+			break
+		}
+		if queue.enqueue(scanJob{target: link, parent: resp.url.String(), depth: job.depth + 1, source: "crawl", input: job.input}) {
+			queued++
+		} else {
+			releaseCrawlSlot(u.Host)
+		}
+	}
+	return queued
+}
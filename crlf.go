@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// crlfFinding is one successful header injection
+type crlfFinding struct {
+	point    string // "path" or "param:<name>"
+	payload  string // The CRLF sequence that worked, as sent (URL-encoded)
+	url      string // Full request URL to reproduce the finding
+	evidence string // What showed up in the response
+}
+
+// crlfResult holds the outcome of the CRLF injection check for a target
+type crlfResult struct {
+	target   string
+	findings []crlfFinding
+	err      error
+}
+
+// crlfPayloads are inserted verbatim into the request-URI. They are already URL-encoded,
+// since Go refuses to send raw control characters in a request line.
+var crlfPayloads = []string{
+	"%0d%0a",
+	"%0D%0A%20",
+	"%0a",
+	"%0d",
+	"%E5%98%8A%E5%98%8D", // U+560A U+560D, truncated to \n\r by some servers
+	"%23%0d%0a",          // Encoded '#' first to slip past naive fragment handling
+	"%3f%0d%0a",          // Encoded '?' first to land after a path/query split
+}
+
+// crlfParams are common query parameters whose values end up in redirect/Set-Cookie headers
+var crlfParams = []string{"url", "redirect", "next", "return", "q", "lang"}
+
+// checkCRLF injects CRLF sequences into the path and common query parameters and looks
+// for a canary header or cookie appearing in the response. It uses the shared client so
+// it goes through the same transport as scanTarget; the raw request-URI is set via
+// URL.Opaque so Go's path normalisation cannot decode or re-escape the payloads.
+func checkCRLF(target string, mainResp *probeResponse, client *http.Client) crlfResult {
+	result := crlfResult{target: target}
+	canary := randomToken(6)
+	injected := "X-Hx-Inject:" + canary
+	cookieInjected := "Set-Cookie:hxinject=" + canary
+
+	basePath := mainResp.url.EscapedPath()
+	if basePath == "" {
+		basePath = "/"
+	}
+
+	type injectionPoint struct {
+		name  string
+		build func(payload string) (rawPath, rawQuery string)
+	}
+	points := []injectionPoint{{
+		name: "path",
+		build: func(p string) (string, string) {
+			return strings.TrimSuffix(basePath, "/") + "/" + p + injected + p + cookieInjected, mainResp.url.RawQuery
+		},
+	}}
+	for _, param := range crlfParams {
+		points = append(points, injectionPoint{
+			name: "param:" + param,
+			build: func(p string) (string, string) {
+				return basePath, param + "=hx" + p + injected + p + cookieInjected
+			},
+		})
+	}
+
+	var lastErr error
+	sent := 0
+	for _, point := range points {
+		for _, payload := range crlfPayloads {
+			rawPath, rawQuery := point.build(payload)
+			resp, err := sendRawURIRequest(client, mainResp, rawPath, rawQuery)
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			sent++
+
+			evidence := ""
+			if v := resp.Header.Get("X-Hx-Inject"); v != "" && strings.Contains(v, canary) {
+				evidence = "header X-Hx-Inject: " + v
+			}
+			for _, c := range resp.Header.Values("Set-Cookie") {
+				if strings.Contains(c, "hxinject="+canary) {
+					if evidence != "" {
+						evidence += "; "
+					}
+					evidence += "Set-Cookie: " + c
+					break
+				}
+			}
+			if evidence != "" {
+				result.findings = append(result.findings, crlfFinding{
+					point:    point.name,
+					payload:  payload,
+					url:      rawRequestURL(mainResp, rawPath, rawQuery),
+					evidence: evidence,
+				})
+				break // One working payload per injection point is enough
+			}
+		}
+	}
+
+	// Only report an error if nothing could be sent at all
+	if sent == 0 && lastErr != nil {
+		result.err = fmt.Errorf("CRLF check could not reach %s: %w", mainResp.url.String(), lastErr)
+	}
+	return result
+}
+
+// sendRawURIRequest sends a GET whose request-URI is exactly rawPath[?rawQuery]
+func sendRawURIRequest(client *http.Client, mainResp *probeResponse, rawPath, rawQuery string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", mainResp.url.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CRLF request for %s: %w", mainResp.url.String(), err)
+	}
+	// Opaque takes precedence over Path when writing the request line, so the
+	// payload bytes go out as-is (RequestURI() returns Opaque + "?" + RawQuery)
+	req.URL.Opaque = rawPath
+	req.URL.RawPath = ""
+	req.URL.RawQuery = rawQuery
+	req.Header.Set("User-Agent", "HyperScanner/1.4+CRLFCheck")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxBodySize))
+	resp.Body.Close()
+	return resp, nil
+}
+
+// rawRequestURL rebuilds the reproducible URL for a raw request-URI
+func rawRequestURL(mainResp *probeResponse, rawPath, rawQuery string) string {
+	u := mainResp.url.Scheme + "://" + mainResp.url.Host + rawPath
+	if rawQuery != "" {
+		u += "?" + rawQuery
+	}
+	return u
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// fallbackNameservers are used when no resolver is configured and none can be read from the system
+var fallbackNameservers = []string{"8.8.8.8:53", "1.1.1.1:53"}
+
+// dnsClient sends queries straight to configured DNS servers, round-robin.
+// Unlike net.Resolver it exposes the full answer section (every CNAME in a chain)
+// and the response code, so NXDOMAIN can be told apart from other failures.
+type dnsClient struct {
+	servers []string
+	next    uint32 // Round-robin index, accessed atomically
+	timeout time.Duration
+}
+
+// dnsAnswer is the parsed answer to a single query
+type dnsAnswer struct {
+	rcode  dnsmessage.RCode
+	cnames []string // CNAME chain in answer order, starting at the queried name
+	a      []net.IP
+	aaaa   []net.IP
+}
+
+// newDNSClient creates a client for the given servers ("ip" or "ip:port").
+// An empty list falls back to the system nameservers.
+func newDNSClient(servers []string, timeout time.Duration) *dnsClient {
+	var normalized []string
+	for _, s := range servers {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(s); err != nil {
+			s = net.JoinHostPort(strings.Trim(s, "[]"), "53")
+		}
+		normalized = append(normalized, s)
+	}
+	if len(normalized) == 0 {
+		normalized = systemNameservers()
+	}
+	return &dnsClient{servers: normalized, timeout: timeout}
+}
+
+// systemNameservers reads nameserver lines from /etc/resolv.conf, falling back to public resolvers
+func systemNameservers() []string {
+	f, err := os.Open("/etc/resolv.conf")
+	if err != nil {
+		return fallbackNameservers
+	}
+	defer f.Close()
+
+	var servers []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "nameserver" {
+			servers = append(servers, net.JoinHostPort(fields[1], "53"))
+		}
+	}
+	if len(servers) == 0 {
+		return fallbackNameservers
+	}
+	return servers
+}
+
+// pickServer returns the next server in round-robin order
+func (c *dnsClient) pickServer() string {
+	i := atomic.AddUint32(&c.next, 1) - 1
+	return c.servers[int(i)%len(c.servers)]
+}
+
+// query resolves one name/type pair. NXDOMAIN is not an error: check answer.rcode.
+// Truncated UDP answers are retried over TCP.
+func (c *dnsClient) query(ctx context.Context, name string, qtype dnsmessage.Type) (*dnsAnswer, error) {
+	fqdn := name
+	if !strings.HasSuffix(fqdn, ".") {
+		fqdn += "."
+	}
+	qname, err := dnsmessage.NewName(fqdn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DNS name %q: %w", name, err)
+	}
+
+	id := uint16(rand.Uint32())
+	msg := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: qname, Type: qtype, Class: dnsmessage.ClassINET}},
+	}
+	packed, err := msg.Pack()
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack DNS query for %s: %w", name, err)
+	}
+
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	server := c.pickServer()
+	raw, err := c.exchange(ctx, "udp", server, packed)
+	if err != nil {
+		return nil, err
+	}
+	var resp dnsmessage.Message
+	if err := resp.Unpack(raw); err != nil {
+		return nil, fmt.Errorf("failed to parse DNS response from %s: %w", server, err)
+	}
+	if resp.Header.Truncated {
+		if raw, err = c.exchange(ctx, "tcp", server, packed); err != nil {
+			return nil, err
+		}
+		if err := resp.Unpack(raw); err != nil {
+			return nil, fmt.Errorf("failed to parse DNS response from %s: %w", server, err)
+		}
+	}
+	if resp.Header.ID != id {
+		return nil, fmt.Errorf("DNS response ID mismatch from %s", server)
+	}
+
+	answer := &dnsAnswer{rcode: resp.Header.RCode}
+	for _, rr := range resp.Answers {
+		switch body := rr.Body.(type) {
+		case *dnsmessage.CNAMEResource:
+			answer.cnames = append(answer.cnames, strings.TrimSuffix(body.CNAME.String(), "."))
+		case *dnsmessage.AResource:
+			answer.a = append(answer.a, net.IP(body.A[:]))
+		case *dnsmessage.AAAAResource:
+			answer.aaaa = append(answer.aaaa, net.IP(body.AAAA[:]))
+		}
+	}
+	return answer, nil
+}
+
+// exchange sends a packed query over udp or tcp and returns the raw reply
+func (c *dnsClient) exchange(ctx context.Context, network, server string, packed []byte) ([]byte, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, network, server)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach DNS server %s: %w", server, err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if network == "tcp" {
+		// DNS over TCP prefixes every message with its length
+		framed := make([]byte, 2+len(packed))
+		binary.BigEndian.PutUint16(framed, uint16(len(packed)))
+		copy(framed[2:], packed)
+		if _, err := conn.Write(framed); err != nil {
+			return nil, fmt.Errorf("DNS query to %s failed: %w", server, err)
+		}
+		var length [2]byte
+		if _, err := io.ReadFull(conn, length[:]); err != nil {
+			return nil, fmt.Errorf("DNS response from %s failed: %w", server, err)
+		}
+		reply := make([]byte, binary.BigEndian.Uint16(length[:]))
+		if _, err := io.ReadFull(conn, reply); err != nil {
+			return nil, fmt.Errorf("DNS response from %s failed: %w", server, err)
+		}
+		return reply, nil
+	}
+
+	if _, err := conn.Write(packed); err != nil {
+		return nil, fmt.Errorf("DNS query to %s failed: %w", server, err)
+	}
+	buf := make([]byte, 4096)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, fmt.Errorf("DNS response from %s failed: %w", server, err)
+	}
+	return buf[:n], nil
+}
+
+// cnameChain resolves the CNAME chain for a host. A recursive resolver returns the whole
+// chain in the answer to an A query, even when the final name does not exist (the classic
+// dangling CNAME), so one query is usually enough. The rcode is for the final name.
+func (c *dnsClient) cnameChain(ctx context.Context, host string) ([]string, dnsmessage.RCode, error) {
+	answer, err := c.query(ctx, host, dnsmessage.TypeA)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(answer.cnames) == 0 && answer.rcode == dnsmessage.RCodeSuccess && len(answer.a) == 0 {
+		// Some resolvers only return the CNAME itself when asked for it explicitly
+		if cnameAnswer, err := c.query(ctx, host, dnsmessage.TypeCNAME); err == nil && len(cnameAnswer.cnames) > 0 {
+			return cnameAnswer.cnames, answer.rcode, nil
+		}
+	}
+	return answer.cnames, answer.rcode, nil
+}
+
+// dnsResult is what was recorded about a target's hostname
+type dnsResult struct {
+	host   string
+	status string // NOERROR, NXDOMAIN, SERVFAIL, REFUSED, TIMEOUT or ERROR
+	cnames []string
+	a      []net.IP
+	aaaa   []net.IP
+	err    error
+}
+
+// lookup resolves A and AAAA records for a host and classifies the outcome
+func (c *dnsClient) lookup(ctx context.Context, host string) *dnsResult {
+	result := &dnsResult{host: host}
+	answer, err := c.query(ctx, host, dnsmessage.TypeA)
+	if err != nil {
+		result.err = err
+		result.status = "ERROR"
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			result.status = "TIMEOUT"
+		}
+		return result
+	}
+	result.status = rcodeName(answer.rcode)
+	result.cnames = answer.cnames
+	result.a = answer.a
+
+	if answer.rcode == dnsmessage.RCodeSuccess {
+		if v6, err := c.query(ctx, host, dnsmessage.TypeAAAA); err == nil {
+			result.aaaa = v6.aaaa
+		}
+	}
+	return result
+}
+
+// rcodeName maps DNS response codes to the names used in output files
+func rcodeName(rcode dnsmessage.RCode) string {
+	switch rcode {
+	case dnsmessage.RCodeSuccess:
+		return "NOERROR"
+	case dnsmessage.RCodeNameError:
+		return "NXDOMAIN"
+	case dnsmessage.RCodeServerFailure:
+		return "SERVFAIL"
+	case dnsmessage.RCodeRefused:
+		return "REFUSED"
+	default:
+		return strings.TrimPrefix(rcode.String(), "RCode")
+	}
+}
+
+// netResolver returns a net.Resolver that sends every lookup to the configured
+// servers (round-robin), for use in the HTTP transport's dialer
+func (c *dnsClient) netResolver() *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true, // Required for Dial to be honoured
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, c.pickServer())
+		},
+	}
+}
+
+// parseResolverList accepts either a comma-separated list of servers or a path to a
+// file with one server per line (blank lines and # comments ignored)
+func parseResolverList(v string) ([]string, error) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return nil, nil
+	}
+	if info, err := os.Stat(v); err == nil && !info.IsDir() {
+		lines, err := readTargetsFromFile(v)
+		if err != nil {
+			return nil, err
+		}
+		var servers []string
+		for _, line := range lines {
+			if !strings.HasPrefix(line, "#") {
+				servers = append(servers, line)
+			}
+		}
+		return servers, nil
+	}
+	return strings.Split(v, ","), nil
+}
+
+// describeDNS formats a DNS result for the dns_records.txt file
+func describeDNS(r *dnsResult) string {
+	parts := []string{"status=" + r.status}
+	if len(r.cnames) > 0 {
+		parts = append(parts, "CNAME="+strings.Join(r.cnames, "->"))
+	}
+	if len(r.a) > 0 {
+		parts = append(parts, "A="+joinIPs(r.a))
+	}
+	if len(r.aaaa) > 0 {
+		parts = append(parts, "AAAA="+joinIPs(r.aaaa))
+	}
+	if r.err != nil {
+		parts = append(parts, "error="+r.err.Error())
+	}
+	return strings.Join(parts, " ")
+}
+
+// joinIPs formats a list of IPs as a comma-separated string
+func joinIPs(ips []net.IP) string {
+	s := make([]string, len(ips))
+	for i, ip := range ips {
+		s[i] = ip.String()
+	}
+	return strings.Join(s, ",")
+}
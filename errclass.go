@@ -0,0 +1,224 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// errorClass is the fixed taxonomy scan failures are sorted into
+type errorClass int
+
+const (
+	errClassNone errorClass = iota
+	errClassDNSNXDomain
+	errClassDNSTimeout
+	errClassTCPRefused
+	errClassTCPTimeout
+	errClassTLSHandshake
+	errClassTLSCertInvalid
+	errClassHTTPProtocol
+	errClassReadTimeout
+	errClassInvalidTarget
+	errClassOutOfScope
+	errClassOther // Anything that doesn't fit the classes above
+)
+
+// errorClassNames doubles as the file name under errors/ (e.g. errors/dns_nxdomain.txt)
+var errorClassNames = map[errorClass]string{
+	errClassNone:           "none",
+	errClassDNSNXDomain:    "dns_nxdomain",
+	errClassDNSTimeout:     "dns_timeout",
+	errClassTCPRefused:     "tcp_refused",
+	errClassTCPTimeout:     "tcp_timeout",
+	errClassTLSHandshake:   "tls_handshake",
+	errClassTLSCertInvalid: "tls_cert_invalid",
+	errClassHTTPProtocol:   "http_protocol",
+	errClassReadTimeout:    "read_timeout",
+	errClassInvalidTarget:  "invalid_target",
+	errClassOutOfScope:     "out_of_scope",
+	errClassOther:          "other",
+}
+
+// errorClassLabels are used in the summary breakdown
+var errorClassLabels = map[errorClass]string{
+	errClassDNSNXDomain:    "DNS NXDOMAIN",
+	errClassDNSTimeout:     "DNS Timeout",
+	errClassTCPRefused:     "TCP Refused",
+	errClassTCPTimeout:     "TCP Timeout",
+	errClassTLSHandshake:   "TLS Handshake Failure",
+	errClassTLSCertInvalid: "TLS Certificate Invalid",
+	errClassHTTPProtocol:   "HTTP Protocol Error",
+	errClassReadTimeout:    "Read Timeout",
+	errClassInvalidTarget:  "Invalid Target",
+	errClassOutOfScope:     "Out of Scope",
+	errClassOther:          "Other",
+}
+
+func (c errorClass) String() string {
+	return errorClassNames[c]
+}
+
+// retryable reports whether a re-scan has a realistic chance of a different outcome.
+// A missing domain, a closed port, a bad certificate, a malformed or out-of-scope target won't fix itself.
+func (c errorClass) retryable() bool {
+	switch c {
+	case errClassDNSNXDomain, errClassTCPRefused, errClassTLSCertInvalid, errClassInvalidTarget, errClassOutOfScope:
+		return false
+	default:
+		return true
+	}
+}
+
+// errInvalidTarget marks errors caused by the input line itself rather than the network
+var errInvalidTarget = errors.New("invalid target format")
+
+// classifyError maps a scan error onto the taxonomy. dns is optional; when the
+// target's DNS answers were recorded they settle NXDOMAIN vs other DNS failures.
+func classifyError(err error, dns *dnsResult) errorClass {
+	if err == nil {
+		return errClassNone
+	}
+	if errors.Is(err, errInvalidTarget) {
+		return errClassInvalidTarget
+	}
+	if errors.Is(err, errOutOfScope) { // From scope.go
+		return errClassOutOfScope
+	}
+
+	// --- DNS ---
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		switch {
+		case dnsErr.IsNotFound:
+			return errClassDNSNXDomain
+		case dnsErr.IsTimeout:
+			return errClassDNSTimeout
+		}
+		if dns != nil && dns.status == "NXDOMAIN" {
+			return errClassDNSNXDomain
+		}
+		if dns != nil && dns.status == "TIMEOUT" {
+			return errClassDNSTimeout
+		}
+		return errClassOther
+	}
+
+	// --- TLS certificate problems (checked before generic TLS errors) ---
+	var unknownAuth x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var certInvalid x509.CertificateInvalidError
+	var verifyErr *tls.CertificateVerificationError
+	if errors.As(err, &unknownAuth) || errors.As(err, &hostnameErr) ||
+		errors.As(err, &certInvalid) || errors.As(err, &verifyErr) {
+		return errClassTLSCertInvalid
+	}
+
+	// --- TCP connect ---
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return errClassTCPRefused
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" && opErr.Timeout() {
+		return errClassTCPTimeout
+	}
+
+	// --- TLS handshake ---
+	var recordErr tls.RecordHeaderError
+	var alertErr tls.AlertError
+	msg := err.Error()
+	if errors.As(err, &recordErr) || errors.As(err, &alertErr) ||
+		strings.Contains(msg, "TLS handshake timeout") || strings.Contains(msg, "tls: ") {
+		return errClassTLSHandshake
+	}
+
+	// --- Timeouts after the connection was up ---
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return errClassReadTimeout
+	}
+
+	// --- HTTP protocol ---
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) ||
+		strings.Contains(msg, "malformed HTTP") || strings.Contains(msg, "HTTP response to HTTPS client") ||
+		strings.Contains(msg, "http2:") || strings.Contains(msg, "stopped after") {
+		return errClassHTTPProtocol
+	}
+
+	return errClassOther
+}
+
+// --- Error class tracking (for the summary and re-scan decisions) ---
+var errorClassCounts = make(map[errorClass]int64)
+var failedTargetClasses = make(map[string]errorClass)
+var errorClassMutex sync.Mutex
+
+// recordErrorClass stores the latest class for a failed target, replacing any earlier one
+func recordErrorClass(target string, class errorClass) {
+	errorClassMutex.Lock()
+	defer errorClassMutex.Unlock()
+	if old, ok := failedTargetClasses[target]; ok {
+		errorClassCounts[old]--
+	}
+	failedTargetClasses[target] = class
+	errorClassCounts[class]++
+}
+
+// clearErrorClass removes a target that succeeded on re-scan
+func clearErrorClass(target string) {
+	errorClassMutex.Lock()
+	defer errorClassMutex.Unlock()
+	if old, ok := failedTargetClasses[target]; ok {
+		errorClassCounts[old]--
+		delete(failedTargetClasses, target)
+	}
+}
+
+// splitRetryable partitions failed targets into those worth re-scanning and the rest
+func splitRetryable(targets []string) (retry []string, skipped map[errorClass]int) {
+	errorClassMutex.Lock()
+	defer errorClassMutex.Unlock()
+	skipped = make(map[errorClass]int)
+	for _, t := range targets {
+		class, ok := failedTargetClasses[t]
+		if !ok || class.retryable() {
+			retry = append(retry, t)
+		} else {
+			skipped[class]++
+		}
+	}
+	return retry, skipped
+}
+
+// printErrorBreakdown shows failures per error class, alongside the status code table
+func printErrorBreakdown() {
+	errorClassMutex.Lock()
+	defer errorClassMutex.Unlock()
+
+	classes := make([]errorClass, 0, len(errorClassCounts))
+	for class, count := range errorClassCounts {
+		if count > 0 {
+			classes = append(classes, class)
+		}
+	}
+	if len(classes) == 0 {
+		return
+	}
+	sort.Slice(classes, func(i, j int) bool { return classes[i] < classes[j] })
+
+	fmt.Println("\nError Breakdown:")
+	for _, class := range classes {
+		retry := ""
+		if !class.retryable() {
+			retry = " (not retried)"
+		}
+		fmt.Printf("  %s%-25s%s : %d%s\n", ColorError, errorClassLabels[class], ColorReset, errorClassCounts[class], retry)
+	}
+}
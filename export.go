@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// exportColumn is one column of the -csv/-md exports
+type exportColumn struct {
+	name   string
+	header string
+	value  func(res scanResult) string
+}
+
+// durationMs formats one timing phase in milliseconds ("" when there is no timing)
+func durationMs(res scanResult, phase func(*probeTiming) int64) string {
+	if res.timing == nil {
+		return ""
+	}
+	return strconv.FormatInt(phase(res.timing), 10)
+}
+
+// exportColumns are the columns -columns can pick from, in their default order
+var exportColumns = []exportColumn{
+	{"target", "Target", func(r scanResult) string { return r.target }},
+	{"url", "URL", func(r scanResult) string { return r.url }},
+	{"status", "Status", func(r scanResult) string {
+		if r.err != nil {
+			return ""
+		}
+		return strconv.Itoa(r.statusCode)
+	}},
+	{"description", "Description", func(r scanResult) string {
+		if r.err != nil {
+			return r.err.Error()
+		}
+		return statusCodes[r.statusCode]
+	}},
+	{"error", "Error Class", func(r scanResult) string {
+		if r.err == nil {
+			return ""
+		}
+		return r.errClass.String()
+	}},
+	{"title", "Title", func(r scanResult) string { return r.title }},
+	{"length", "Length", func(r scanResult) string {
+		if r.err != nil {
+			return ""
+		}
+		return strconv.Itoa(r.length)
+	}},
+	{"cors", "CORS", func(r scanResult) string {
+		switch {
+		case r.cors == nil:
+			return "" // Not checked
+		case r.cors.err != nil:
+			return "error"
+		case r.cors.vulnerable:
+			return corsSeverity(r.cors.details) // From report.go
+		}
+		return "none"
+	}},
+	{"time", "Total ms", func(r scanResult) string {
+		return durationMs(r, func(t *probeTiming) int64 { return t.total.Milliseconds() })
+	}},
+	{"ttfb", "TTFB ms", func(r scanResult) string {
+		return durationMs(r, func(t *probeTiming) int64 { return t.ttfb.Milliseconds() })
+	}},
+	{"dns", "DNS ms", func(r scanResult) string {
+		return durationMs(r, func(t *probeTiming) int64 { return t.dns.Milliseconds() })
+	}},
+	{"connect", "Connect ms", func(r scanResult) string {
+		return durationMs(r, func(t *probeTiming) int64 { return t.connect.Milliseconds() })
+	}},
+	{"tls", "TLS ms", func(r scanResult) string {
+		return durationMs(r, func(t *probeTiming) int64 { return t.tls.Milliseconds() })
+	}},
+	{"tech", "Tech", func(r scanResult) string { return strings.Join(r.tech, "; ") }},
+	{"source", "Source", func(r scanResult) string { return r.source }},
+	{"input", "Input", func(r scanResult) string { return r.input }},
+}
+
+// defaultExportColumns is used when -columns isn't given
+const defaultExportColumns = "target,url,status,description,error,title,length,cors,time"
+
+// exportColumnNames lists every column for help and error messages
+func exportColumnNames() string {
+	names := make([]string, len(exportColumns))
+	for i, c := range exportColumns {
+		names[i] = c.name
+	}
+	return strings.Join(names, ",")
+}
+
+// parseExportColumns resolves a comma-separated -columns value
+func parseExportColumns(spec string) ([]exportColumn, error) {
+	var columns []exportColumn
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		found := false
+		for _, c := range exportColumns {
+			if c.name == name {
+				columns = append(columns, c)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown -columns entry %q (use %s)", name, exportColumnNames())
+		}
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("-columns selects no columns (use %s)", exportColumnNames())
+	}
+	return columns, nil
+}
+
+// resultExporter appends one row per result to the -csv and -md files as results
+// arrive, so partial results can be opened while a long scan is still running.
+// Re-scan results are added as new rows (their source column reads "rescan").
+type resultExporter struct {
+	columns []exportColumn
+	csvPath string // "" = no CSV export
+	mdPath  string // "" = no Markdown export
+}
+
+// newResultExporter truncates the export files and writes their headers. It is only
+// called once the output directory is set up, so a run that stops earlier (a bad flag,
+// a refused output directory) leaves existing export files alone.
+func newResultExporter(csvPath, mdPath string, columns []exportColumn) (*resultExporter, error) {
+	e := &resultExporter{columns: columns, csvPath: csvPath, mdPath: mdPath}
+	headers := make([]string, len(columns))
+	for i, c := range columns {
+		headers[i] = c.header
+	}
+	for _, path := range []string{csvPath, mdPath} {
+		if path == "" {
+			continue
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			return nil, fmt.Errorf("failed to create export file %s: %w", path, err)
+		}
+	}
+	if csvPath != "" {
+		appendToFile(csvPath, csvLine(headers))
+	}
+	if mdPath != "" {
+		appendToFile(mdPath, markdownLine(headers))
+		appendToFile(mdPath, "|"+strings.Repeat(" --- |", len(headers)))
+	}
+	return e, nil
+}
+
+// write adds a result to every export file. Filtered results are left out, as they
+// are everywhere else results are reported.
+func (e *resultExporter) write(res scanResult) {
+	if e == nil || (res.err == nil && res.filtered != "") {
+		return
+	}
+	values := make([]string, len(e.columns))
+	for i, c := range e.columns {
+		values[i] = c.value(res)
+	}
+	if e.csvPath != "" {
+		cells := make([]string, len(values))
+		for i, v := range values {
+			cells[i] = csvSafe(v)
+		}
+		appendToFile(e.csvPath, csvLine(cells))
+	}
+	if e.mdPath != "" {
+		appendToFile(e.mdPath, markdownLine(values))
+	}
+}
+
+// csvLine formats one CSV record (without the trailing newline appendToFile adds)
+func csvLine(fields []string) string {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write(fields)
+	w.Flush()
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// csvSafe defuses values a spreadsheet would run as a formula. Titles and error
+// messages come from the scanned servers, so "=HYPERLINK(...)" must stay text.
+func csvSafe(v string) string {
+	if v != "" && strings.ContainsRune("=+-@\t\r", rune(v[0])) {
+		if _, err := strconv.ParseFloat(v, 64); err != nil { // Keep negative numbers numeric
+			return "'" + v
+		}
+	}
+	return v
+}
+
+// markdownLine formats one table row, escaping pipes and flattening line breaks
+func markdownLine(fields []string) string {
+	cells := make([]string, len(fields))
+	for i, f := range fields {
+		f = strings.Join(strings.Fields(f), " ")
+		cells[i] = strings.ReplaceAll(strings.ReplaceAll(f, `\`, `\\`), "|", `\|`)
+	}
+	return "| " + strings.Join(cells, " | ") + " |"
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// extractRule pulls one kind of data out of responses. When the pattern has a
+// capture group, group 1 is kept; otherwise the whole match.
+type extractRule struct {
+	name    string // Also the file name under extracted/
+	pattern *regexp.Regexp
+	valid   func(string) bool // Optional post-filter for matches the regex alone can't rule out
+}
+
+// builtinExtractRules are the named rules -extract accepts ("all" selects every one)
+var builtinExtractRules = []extractRule{
+	{name: "emails", pattern: regexp.MustCompile(`[a-zA-Z0-9._%+-]+@[a-zA-Z0-9-]+(?:\.[a-zA-Z0-9-]+)*\.[a-zA-Z]{2,}`), valid: plausibleEmail},
+	{name: "aws-keys", pattern: regexp.MustCompile(`\b(?:AKIA|ASIA|AGPA|AIDA|AROA|ANPA|ANVA)[0-9A-Z]{16}\b`)},
+	{name: "jwt", pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{5,}\.eyJ[A-Za-z0-9_-]{5,}\.[A-Za-z0-9_-]{10,}`)},
+	{name: "internal-ips", pattern: regexp.MustCompile(`\b(?:10|127|172|192)\.\d{1,3}\.\d{1,3}\.\d{1,3}\b`), valid: privateIPv4},
+	{name: "s3-buckets", pattern: regexp.MustCompile(`(?i)\b(?:[a-z0-9.-]+\.s3(?:[.-][a-z0-9-]+)?\.amazonaws\.com|s3(?:[.-][a-z0-9-]+)?\.amazonaws\.com/[a-z0-9.-]+|s3://[a-z0-9.-]+)`)},
+}
+
+// extractRuleName restricts custom rule names to something safe to use as a file name
+var extractRuleName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// extractFlag collects repeated -extract flags. Each value is either a comma-separated
+// list of built-in rule names, or a custom "name:regex" rule (the regex may contain commas).
+type extractFlag []string
+
+func (e *extractFlag) String() string { return strings.Join(*e, " ") }
+
+func (e *extractFlag) Set(v string) error {
+	*e = append(*e, v)
+	return nil
+}
+
+// parseExtractRules turns the -extract values into rules, rejecting unknown
+// built-ins, bad regexes and duplicate names
+func parseExtractRules(values extractFlag) ([]extractRule, error) {
+	var rules []extractRule
+	seen := make(map[string]bool)
+	add := func(r extractRule) error {
+		if seen[r.name] {
+			return fmt.Errorf("duplicate -extract rule %q", r.name)
+		}
+		seen[r.name] = true
+		rules = append(rules, r)
+		return nil
+	}
+
+	for _, v := range values {
+		if name, expr, ok := strings.Cut(v, ":"); ok {
+			if !extractRuleName.MatchString(name) {
+				return nil, fmt.Errorf("-extract rule name %q may only contain letters, digits, '-' and '_'", name)
+			}
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid -extract regex for %s: %w", name, err)
+			}
+			if err := add(extractRule{name: name, pattern: re}); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		for _, name := range strings.Split(v, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			if name == "all" {
+				for _, r := range builtinExtractRules {
+					if !seen[r.name] {
+						add(r)
+					}
+				}
+				continue
+			}
+			found := false
+			for _, r := range builtinExtractRules {
+				if r.name == name {
+					found = true
+					if err := add(r); err != nil {
+						return nil, err
+					}
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("unknown -extract rule %q (built-ins: %s, all; or name:regex)", name, builtinExtractNames())
+			}
+		}
+	}
+	return rules, nil
+}
+
+// builtinExtractNames lists the built-in rule names for help and error messages
+func builtinExtractNames() string {
+	names := make([]string, len(builtinExtractRules))
+	for i, r := range builtinExtractRules {
+		names[i] = r.name
+	}
+	return strings.Join(names, ", ")
+}
+
+// extractData applies every rule to the response headers and body and returns the
+// unique values found per rule (rules with no matches are left out)
+func extractData(resp *probeResponse, rules []extractRule) map[string][]string {
+	var sb strings.Builder
+	for name, values := range resp.header {
+		for _, v := range values {
+			sb.WriteString(name + ": " + v + "\n")
+		}
+	}
+	sb.WriteString("\n")
+	sb.Write(resp.body)
+	text := sb.String()
+
+	found := make(map[string][]string)
+	for _, r := range rules {
+		seen := make(map[string]bool)
+		for _, m := range r.pattern.FindAllStringSubmatch(text, -1) {
+			value := m[0]
+			if len(m) > 1 {
+				value = m[1]
+			}
+			if value == "" || seen[value] || (r.valid != nil && !r.valid(value)) {
+				continue
+			}
+			seen[value] = true
+			found[r.name] = append(found[r.name], value)
+		}
+		sort.Strings(found[r.name])
+	}
+	return found
+}
+
+// plausibleEmail drops common false positives such as retina image names (logo@2x.png)
+func plausibleEmail(v string) bool {
+	lower := strings.ToLower(v)
+	for _, ext := range []string{".png", ".jpg", ".jpeg", ".gif", ".svg", ".webp", ".css", ".js"} {
+		if strings.HasSuffix(lower, ext) {
+			return false
+		}
+	}
+	return true
+}
+
+// privateIPv4 keeps RFC 1918 and loopback addresses (and rejects octets over 255)
+func privateIPv4(v string) bool {
+	ip := net.ParseIP(v)
+	return ip != nil && (ip.IsPrivate() || ip.IsLoopback())
+}
+
+// --- Global de-duplication of extracted values (across all targets) ---
+var extractedSeen = make(map[string]map[string]bool) // rule -> value -> seen
+var extractedSeenMutex sync.Mutex
+
+// claimExtracted returns the values not reported for this rule before and marks them as seen
+func claimExtracted(rule string, values []string) []string {
+	extractedSeenMutex.Lock()
+	defer extractedSeenMutex.Unlock()
+	if extractedSeen[rule] == nil {
+		extractedSeen[rule] = make(map[string]bool)
+	}
+	var fresh []string
+	for _, v := range values {
+		if !extractedSeen[rule][v] {
+			extractedSeen[rule][v] = true
+			fresh = append(fresh, v)
+		}
+	}
+	return fresh
+}
+
+// printExtractSummary shows how many unique values each rule found
+func printExtractSummary() {
+	extractedSeenMutex.Lock()
+	defer extractedSeenMutex.Unlock()
+	if len(extractedSeen) == 0 {
+		return
+	}
+	rules := make([]string, 0, len(extractedSeen))
+	for rule := range extractedSeen {
+		rules = append(rules, rule)
+	}
+	sort.Strings(rules)
+	fmt.Println("\nExtracted Data (unique values):")
+	for _, rule := range rules {
+		fmt.Printf("  %s%-25s%s : %d\n", ColorAccent, rule, ColorReset, len(extractedSeen[rule]))
+	}
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/bits"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// faviconResult holds the favicon hashes computed for a live target
+type faviconResult struct {
+	target  string
+	iconURL string // Where the icon was finally fetched from
+	found   bool   // False if no icon could be retrieved (not an error)
+	mmh3    int32  // Shodan-style hash: mmh3 of the base64-encoded body
+	md5     string
+	sha256  string
+	size    int
+	err     error
+}
+
+// maxFaviconRedirects limits how many redirects are followed when fetching an icon
+const maxFaviconRedirects = 3
+
+// Matches <link ...> tags; rel/href are pulled out separately since attribute order varies
+var linkTagRegex = regexp.MustCompile(`(?is)<link\b[^>]*>`)
+
+// --- Favicon hash grouping (for the summary) ---
+var faviconGroups = make(map[int32][]string)
+var faviconGroupsMutex sync.Mutex
+
+// checkFavicon locates and hashes the favicon of a target whose main page was already fetched.
+// The <link rel=icon> href from the main page is preferred, falling back to /favicon.ico.
+func checkFavicon(target string, mainResp *probeResponse, client *http.Client) faviconResult {
+	result := faviconResult{target: target}
+
+	candidates := []string{}
+	if href := findIconHref(mainResp.body); href != "" {
+		if ref, err := url.Parse(href); err == nil {
+			candidates = append(candidates, mainResp.url.ResolveReference(ref).String())
+		}
+	}
+	fallback := mainResp.url.ResolveReference(&url.URL{Path: "/favicon.ico"}).String()
+	if len(candidates) == 0 || candidates[0] != fallback {
+		candidates = append(candidates, fallback)
+	}
+
+	for _, iconURL := range candidates {
+		body, finalURL, err := fetchFavicon(iconURL, client)
+		if err != nil {
+			result.err = err
+			continue
+		}
+		if len(body) == 0 {
+			continue
+		}
+
+		md5Sum := md5.Sum(body)
+		shaSum := sha256.Sum256(body)
+		result.found = true
+		result.err = nil
+		result.iconURL = finalURL
+		result.mmh3 = faviconMMH3(body)
+		result.md5 = hex.EncodeToString(md5Sum[:])
+		result.sha256 = hex.EncodeToString(shaSum[:])
+		result.size = len(body)
+		return result
+	}
+	return result
+}
+
+// fetchFavicon GETs an icon URL, following a few redirects manually since the
+// shared client is configured not to. Returns an empty body for non-200 responses.
+func fetchFavicon(iconURL string, client *http.Client) ([]byte, string, error) {
+	current := iconURL
+	for i := 0; i <= maxFaviconRedirects; i++ {
+		req, err := http.NewRequest("GET", current, nil)
+		if err != nil {
+			return nil, current, fmt.Errorf("failed to create favicon request for %s: %w", current, err)
+		}
+		req.Header.Set("User-Agent", "HyperScanner/1.4+Favicon")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, current, fmt.Errorf("favicon request failed for %s: %w", current, err)
+		}
+		body, readErr := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+		resp.Body.Close()
+
+		if resp.StatusCode >= 300 && resp.StatusCode < 400 {
+			loc, locErr := resp.Location()
+			if locErr != nil {
+				return nil, current, nil // Redirect without a usable Location, treat as missing
+			}
+			current = loc.String()
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, current, nil
+		}
+		if readErr != nil {
+			return nil, current, fmt.Errorf("failed to read favicon body from %s: %w", current, readErr)
+		}
+		return body, current, nil
+	}
+	return nil, current, fmt.Errorf("too many redirects fetching favicon from %s", iconURL)
+}
+
+// findIconHref returns the href of the first <link rel="...icon..."> tag in an HTML page
+func findIconHref(body []byte) string {
+	for _, tag := range linkTagRegex.FindAllString(string(body), -1) {
+		rel := strings.ToLower(htmlAttr(tag, "rel"))
+		if !strings.Contains(rel, "icon") {
+			continue
+		}
+		if href := htmlAttr(tag, "href"); href != "" {
+			return href
+		}
+	}
+	return ""
+}
+
+// Matches name=value attributes with double, single or no quotes around the value
+var htmlAttrRegex = regexp.MustCompile(`([^\s"'<>/=]+)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+
+// htmlAttr extracts a (double, single or un-quoted) attribute value from a single tag
+func htmlAttr(tag, name string) string {
+	for _, m := range htmlAttrRegex.FindAllStringSubmatch(tag, -1) {
+		if !strings.EqualFold(m[1], name) {
+			continue
+		}
+		for _, v := range m[2:] {
+			if v != "" {
+				return strings.TrimSpace(v)
+			}
+		}
+		return ""
+	}
+	return ""
+}
+
+// faviconMMH3 computes the hash used by Shodan's http.favicon.hash filter:
+// MurmurHash3 (x86, 32-bit, seed 0) over the body encoded the way Python's
+// base64.encodebytes does it (76-char lines, each terminated by a newline).
+func faviconMMH3(data []byte) int32 {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	var sb strings.Builder
+	for len(encoded) > 76 {
+		sb.WriteString(encoded[:76])
+		sb.WriteByte('\n')
+		encoded = encoded[76:]
+	}
+	sb.WriteString(encoded)
+	sb.WriteByte('\n')
+	return int32(murmur3x86_32([]byte(sb.String()), 0))
+}
+
+// murmur3x86_32 is a straight port of MurmurHash3_x86_32
+func murmur3x86_32(data []byte, seed uint32) uint32 {
+	const (
+		c1 = 0xcc9e2d51
+		c2 = 0x1b873593
+	)
+	h := seed
+	nblocks := len(data) / 4
+	for i := 0; i < nblocks; i++ {
+		k := binary.LittleEndian.Uint32(data[i*4:])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+		h = bits.RotateLeft32(h, 13)
+		h = h*5 + 0xe6546b64
+	}
+
+	tail := data[nblocks*4:]
+	var k1 uint32
+	switch len(tail) {
+	case 3:
+		k1 ^= uint32(tail[2]) << 16
+		fallthrough
+	case 2:
+		k1 ^= uint32(tail[1]) << 8
+		fallthrough
+	case 1:
+		k1 ^= uint32(tail[0])
+		k1 *= c1
+		k1 = bits.RotateLeft32(k1, 15)
+		k1 *= c2
+		h ^= k1
+	}
+
+	h ^= uint32(len(data))
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return h
+}
+
+// recordFaviconHash adds a target to the group for its favicon hash
+func recordFaviconHash(hash int32, target string) {
+	faviconGroupsMutex.Lock()
+	faviconGroups[hash] = append(faviconGroups[hash], target)
+	faviconGroupsMutex.Unlock()
+}
+
+// printFaviconSummary lists favicon hashes with the hosts sharing them, largest groups first
+func printFaviconSummary() {
+	faviconGroupsMutex.Lock()
+	defer faviconGroupsMutex.Unlock()
+	if len(faviconGroups) == 0 {
+		return
+	}
+
+	hashes := make([]int32, 0, len(faviconGroups))
+	for h := range faviconGroups {
+		hashes = append(hashes, h)
+	}
+	sort.Slice(hashes, func(i, j int) bool {
+		ci, cj := len(faviconGroups[hashes[i]]), len(faviconGroups[hashes[j]])
+		if ci != cj {
+			return ci > cj
+		}
+		return hashes[i] < hashes[j]
+	})
+
+	const maxHostsShown = 5
+	fmt.Println("\nFavicon Hash Groups (mmh3):")
+	for _, h := range hashes {
+		hosts := faviconGroups[h]
+		shown := hosts
+		if len(shown) > maxHostsShown {
+			shown = shown[:maxHostsShown]
+		}
+		more := ""
+		if len(hosts) > maxHostsShown {
+			more = fmt.Sprintf(" (+%d more)", len(hosts)-maxHostsShown)
+		}
+		fmt.Printf("  %s%-12d%s : %d host(s) %s%s\n", ColorAccent, h, ColorReset, len(hosts), strings.Join(shown, ", "), more)
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// responseFilter decides which live results are reported. A result is kept when it
+// satisfies every -match-* option given and none of the -filter-* options.
+type responseFilter struct {
+	matchString string
+	matchRegex  *regexp.Regexp
+	matchCodes  map[int]bool
+	filterCodes map[int]bool
+	filterSizes map[int]bool
+	filterWords map[int]bool
+	filterTitle string // Lower-cased; matched as a substring of the page title
+}
+
+// newResponseFilter builds a filter from the flag values. It returns nil when no
+// option is set, so callers can skip filtering entirely.
+func newResponseFilter(matchString, matchRegex, matchCode, filterCode, filterSize, filterWords, filterTitle string) (*responseFilter, error) {
+	if matchString == "" && matchRegex == "" && matchCode == "" && filterCode == "" &&
+		filterSize == "" && filterWords == "" && filterTitle == "" {
+		return nil, nil
+	}
+	f := &responseFilter{matchString: matchString, filterTitle: strings.ToLower(filterTitle)}
+	var err error
+	if matchRegex != "" {
+		if f.matchRegex, err = regexp.Compile(matchRegex); err != nil {
+			return nil, fmt.Errorf("invalid -match-regex: %w", err)
+		}
+	}
+	if f.matchCodes, err = parseIntSet(matchCode); err != nil {
+		return nil, fmt.Errorf("invalid -match-code: %w", err)
+	}
+	if f.filterCodes, err = parseIntSet(filterCode); err != nil {
+		return nil, fmt.Errorf("invalid -filter-code: %w", err)
+	}
+	if f.filterSizes, err = parseIntSet(filterSize); err != nil {
+		return nil, fmt.Errorf("invalid -filter-size: %w", err)
+	}
+	if f.filterWords, err = parseIntSet(filterWords); err != nil {
+		return nil, fmt.Errorf("invalid -filter-words: %w", err)
+	}
+	return f, nil
+}
+
+// parseIntSet parses a comma-separated list of integers ("" gives an empty set)
+func parseIntSet(v string) (map[int]bool, error) {
+	set := make(map[int]bool)
+	for _, part := range strings.Split(v, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", part)
+		}
+		set[n] = true
+	}
+	return set, nil
+}
+
+// check returns why a response is filtered out, as "<flag>: <detail>", or "" if it is kept.
+// Size is the body length as read (capped at maxBodySize); words are whitespace-separated.
+func (f *responseFilter) check(resp *probeResponse) string {
+	body := string(resp.body)
+	if len(f.matchCodes) > 0 && !f.matchCodes[resp.statusCode] {
+		return fmt.Sprintf("-match-code: status %d", resp.statusCode)
+	}
+	if f.filterCodes[resp.statusCode] {
+		return fmt.Sprintf("-filter-code: status %d", resp.statusCode)
+	}
+	if f.filterSizes[len(resp.body)] {
+		return fmt.Sprintf("-filter-size: %d bytes", len(resp.body))
+	}
+	if words := len(strings.Fields(body)); f.filterWords[words] {
+		return fmt.Sprintf("-filter-words: %d words", words)
+	}
+	if f.filterTitle != "" {
+		if title := extractTitle(resp.body); strings.Contains(strings.ToLower(title), f.filterTitle) {
+			return fmt.Sprintf("-filter-title: %q", title)
+		}
+	}
+	if f.matchString != "" && !strings.Contains(body, f.matchString) {
+		return "-match-string: not found"
+	}
+	if f.matchRegex != nil && !f.matchRegex.Match(resp.body) {
+		return "-match-regex: no match"
+	}
+	return ""
+}
+
+// titlePattern finds the first <title> element of an HTML page
+var titlePattern = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+// extractTitle returns the page title with entities decoded and whitespace collapsed
+func extractTitle(body []byte) string {
+	m := titlePattern.FindSubmatch(body)
+	if m == nil {
+		return ""
+	}
+	return strings.Join(strings.Fields(html.UnescapeString(string(m[1]))), " ")
+}
+
+// --- Filter statistics (for the summary) ---
+var filteredCounts = make(map[string]int64) // Keyed by the flag that removed the result
+var filteredCountsMutex sync.Mutex
+
+// recordFiltered counts a filtered result under the flag named in its reason
+func recordFiltered(reason string) {
+	flagName, _, _ := strings.Cut(reason, ":")
+	filteredCountsMutex.Lock()
+	filteredCounts[flagName]++
+	filteredCountsMutex.Unlock()
+}
+
+// printFilterSummary shows how many live results the match/filter options removed
+func printFilterSummary() {
+	filteredCountsMutex.Lock()
+	defer filteredCountsMutex.Unlock()
+	var total int64
+	kinds := make([]string, 0, len(filteredCounts))
+	for kind, n := range filteredCounts {
+		total += n
+		kinds = append(kinds, kind)
+	}
+	if total == 0 {
+		return
+	}
+	sort.Strings(kinds)
+	fmt.Printf("\n%sFiltered Out: %d%s\n", ColorAccent, total, ColorReset)
+	for _, kind := range kinds {
+		fmt.Printf("  %-25s : %d\n", kind, filteredCounts[kind])
+	}
+}
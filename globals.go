@@ -1,6 +1,12 @@
 package main
 
-import "sync"
+import (
+	"sync"
+	"time"
+)
+
+// version is reported in the banner and in run.json
+const version = "1.4"
 
 // --- ANSI Color Codes ---
 const (
@@ -13,6 +19,7 @@ const (
 	ColorAccent   = "\033[36m"       // Cyan for accents like paths
 	ColorCorsVuln = "\033[38;5;208m" // Orange for CORS Vulnerable
 	ColorCorsErr  = "\033[38;5;198m" // Pinkish for CORS Errors
+	ColorFinding  = "\033[38;5;208m" // Orange for findings from the other active checks
 )
 
 // --- Global Maps (Populated) ---
@@ -68,10 +75,66 @@ type scanResult struct {
 	target     string
 	statusCode int
 	err        error
-	isRescan   bool             // Flag to indicate if this result is from a re-scan
-	cors       *corsCheckResult // Pointer to CORS result (nil if not checked/applicable)
+	errClass   errorClass          // Classification of err (errClassNone on success)
+	isRescan   bool                // Flag to indicate if this result is from a re-scan
+	cors       *corsCheckResult    // Pointer to CORS result (nil if not checked/applicable)
+	favicon    *faviconResult      // Pointer to favicon hash result (nil if not checked)
+	waf        *wafResult          // Pointer to WAF/CDN detection result (nil if not checked)
+	methods    *methodsResult      // Pointer to risky HTTP method result (nil if not checked)
+	crlf       *crlfResult         // Pointer to CRLF injection result (nil if not checked)
+	cache      *cachePoisonResult  // Pointer to cache poisoning result (nil if not checked)
+	takeover   *takeoverResult     // Pointer to subdomain takeover result (nil if not checked)
+	templates  *templateResult     // Pointer to YAML template findings (nil if no templates loaded)
+	filtered   string              // Why -match-*/-filter-* options removed this result ("" = kept)
+	extracted  map[string][]string // Unique -extract values found in this response, per rule
+	js         *jsResult           // Pointer to JavaScript discovery result (nil if not checked)
+	harvest    *harvestResult      // Pointer to robots/sitemap/security.txt harvest (nil unless first URL of its origin)
+	parent     string              // URL this target was discovered on ("" for input targets)
+	depth      int                 // Discovery depth (0 for input targets)
+	source     string              // How the target was found: "input", "rescan", "js", ...
+	input      string              // Label of the input source (file or stdin) the target traces back to
+	dns        *dnsResult          // A/AAAA/CNAME answers for the target's hostname (nil if not recorded)
+	remoteAddr string              // IP:port the primary response came from
+	timing     *probeTiming        // Per-phase latency of the primary request (nil on failure)
+	url        string              // URL the primary request was sent to ("" on failure)
+	length     int                 // Body length of the primary response (as read, up to maxBodySize)
+	title      string              // <title> of the primary response ("" if none)
+	tech       []string            // Server software and frameworks fingerprinted from the primary response
 } // [source: 27]
 
+// --- Options controlling which optional checks run ---
+// Passed to workers and the results processor instead of one flag per check.
+type scanOptions struct {
+	corsCheck     bool             // Run the CORS misconfiguration check on live targets
+	faviconCheck  bool             // Fetch and hash the favicon of live targets
+	wafCheck      bool             // Fingerprint WAFs/CDNs from the primary response
+	wafProbe      bool             // Also send a suspicious-looking probe request (implies wafCheck)
+	methodsCheck  bool             // Enumerate risky HTTP methods (TRACE, PUT, DELETE, overrides)
+	crlfCheck     bool             // Probe the path and common parameters for CRLF/header injection
+	cacheCheck    bool             // Probe caches for poisoning via unkeyed headers
+	takeover      bool             // Match CNAME chains and bodies against takeover fingerprints
+	resolver      *dnsClient       // DNS client used for CNAME lookups and DNS records
+	dnsRecords    bool             // Record A/AAAA/CNAME answers for every target
+	slowThreshold time.Duration    // Flag hosts whose total request time exceeds this (0 = off)
+	report        bool             // Keep every result in memory for the -html report
+	export        *resultExporter  // -csv/-md files results are appended to as they arrive (nil = none)
+	request       *requestConfig   // Method/headers/body/cookie/UA for the primary probe (and CORS headers)
+	raw           *rawTemplate     // Raw request template sent instead of the normal primary probe (-raw)
+	templates     []*checkTemplate // YAML checks run against every live target (-templates)
+	filter        *responseFilter  // Match/filter options for live results (nil = keep everything)
+	extractRules  []extractRule    // -extract rules applied to live response headers and bodies
+	jsCheck       bool             // Mine same-origin JavaScript for endpoints, hostnames and secrets
+	jsEnqueue     bool             // Scan same-host JS endpoints as new targets
+	jsDepth       int              // Discovery depth limit for -js-enqueue (input targets are depth 0)
+	crawl         bool             // Follow same-origin links from live HTML pages
+	crawlDepth    int              // Link depth limit for -crawl (input targets are depth 0)
+	crawlMax      int              // Pages queued per host by the crawler
+	crawlRobots   bool             // Skip paths robots.txt disallows while crawling
+	harvest       bool             // Fetch robots.txt, sitemaps and security.txt once per live origin
+	harvestQueue  bool             // Scan harvested robots.txt paths and sitemap URLs as new targets
+	scope         *scopeRules      // -scope rules; nil when every target is in scope
+}
+
 // --- Global Variables for Tracking Failures ---
 var failedTargets []string
 var failedTargetsMutex sync.Mutex // [source: 27]
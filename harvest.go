@@ -0,0 +1,286 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	maxSitemapsPerHost   = 10   // Sitemap files fetched per host (indexes included)
+	maxSitemapURLs       = 5000 // <loc> entries recorded per host
+	maxHarvestEnqueueURL = 200  // New targets -harvest-enqueue adds per host
+)
+
+// harvestResult holds what was learned from a host's robots.txt, sitemaps and security.txt.
+// It is written as one JSON object per host to harvest.jsonl.
+type harvestResult struct {
+	Origin      string              `json:"origin"`
+	Robots      *harvestRobots      `json:"robots,omitempty"`
+	Sitemaps    []string            `json:"sitemaps,omitempty"`            // Sitemap files that were read
+	OffOrigin   []string            `json:"off_origin_sitemaps,omitempty"` // Sitemaps on another origin, listed but not fetched
+	SitemapURLs []string            `json:"sitemap_urls,omitempty"`        // Page URLs listed in them
+	SecurityTxt map[string][]string `json:"security_txt,omitempty"`        // Field -> values
+	SecurityURL string              `json:"security_txt_url,omitempty"`
+	Errors      []string            `json:"errors,omitempty"`
+	target      string
+}
+
+// harvestRobots is the robots.txt part of a harvest (all user agents)
+type harvestRobots struct {
+	Disallow []string `json:"disallow,omitempty"`
+	Allow    []string `json:"allow,omitempty"`
+	Sitemaps []string `json:"sitemaps,omitempty"`
+}
+
+// sitemapXML covers both <urlset> and <sitemapindex> documents
+type sitemapXML struct {
+	XMLName  xml.Name
+	URLs     []sitemapLoc `xml:"url"`
+	Sitemaps []sitemapLoc `xml:"sitemap"`
+}
+
+type sitemapLoc struct {
+	Loc string `xml:"loc"`
+}
+
+// --- Harvest bookkeeping (each origin is harvested once, whatever the number of URLs on it) ---
+var harvestedOrigins = make(map[string]bool)
+var harvestedOriginsMutex sync.Mutex
+var harvestHostsWithRobots, harvestDisallowed, harvestSitemapURLs, harvestSecurityTxt int64
+
+// claimHarvest reports whether an origin still needs harvesting, marking it as done
+func claimHarvest(origin string) bool {
+	harvestedOriginsMutex.Lock()
+	defer harvestedOriginsMutex.Unlock()
+	if harvestedOrigins[origin] {
+		return false
+	}
+	harvestedOrigins[origin] = true
+	return true
+}
+
+// harvestHost fetches and parses robots.txt, the sitemaps it lists (or /sitemap.xml)
+// and security.txt for the origin of a live target
+func harvestHost(target string, mainResp *probeResponse, client *http.Client, reqCfg *requestConfig) harvestResult {
+	origin := mainResp.url.Scheme + "://" + mainResp.url.Host
+	result := harvestResult{Origin: origin, target: target}
+
+	// --- robots.txt (shared with the crawler's cache, so it is fetched once) ---
+	robots := robotsFor(mainResp.url, client, reqCfg) // From robots.go
+	if robots.found {
+		result.Robots = &harvestRobots{Disallow: robots.allDisallow, Allow: robots.allAllow, Sitemaps: robots.sitemaps}
+	}
+
+	// --- Sitemaps: those robots.txt names, else the conventional location ---
+	queue := append([]string(nil), robots.sitemaps...)
+	if len(queue) == 0 {
+		queue = []string{origin + "/sitemap.xml"}
+	}
+	seenSitemap := make(map[string]bool)
+	seenURL := make(map[string]bool)
+	for len(queue) > 0 && len(result.Sitemaps) < maxSitemapsPerHost {
+		sitemapURL := queue[0]
+		queue = queue[1:]
+		if seenSitemap[sitemapURL] {
+			continue
+		}
+		seenSitemap[sitemapURL] = true
+
+		// Only the harvested origin is fetched: robots.txt and sitemap indexes can point anywhere
+		u, err := mainResp.url.Parse(sitemapURL)
+		if err != nil || !sameOrigin(u, mainResp.url) { // sameOrigin from crawl.go
+			result.OffOrigin = append(result.OffOrigin, sitemapURL)
+			continue
+		}
+		sitemapURL = u.String()
+
+		doc, err := fetchSitemap(sitemapURL, client, reqCfg)
+		if err != nil {
+			if len(robots.sitemaps) > 0 { // A missing default /sitemap.xml is not worth reporting
+				result.Errors = append(result.Errors, err.Error())
+			}
+			continue
+		}
+		result.Sitemaps = append(result.Sitemaps, sitemapURL)
+		for _, s := range doc.Sitemaps {
+			if loc := strings.TrimSpace(s.Loc); loc != "" {
+				queue = append(queue, loc)
+			}
+		}
+		for _, u := range doc.URLs {
+			loc := strings.TrimSpace(u.Loc)
+			if loc != "" && !seenURL[loc] && len(result.SitemapURLs) < maxSitemapURLs {
+				seenURL[loc] = true
+				result.SitemapURLs = append(result.SitemapURLs, loc)
+			}
+		}
+	}
+
+	// --- security.txt (RFC 9116), with the legacy root location as fallback ---
+	for _, p := range []string{"/.well-known/security.txt", "/security.txt"} {
+		fields, err := fetchSecurityTxt(origin+p, client, reqCfg)
+		if err == nil && len(fields) > 0 {
+			result.SecurityTxt = fields
+			result.SecurityURL = origin + p
+			break
+		}
+	}
+
+	if result.Robots != nil {
+		atomic.AddInt64(&harvestHostsWithRobots, 1)
+		atomic.AddInt64(&harvestDisallowed, int64(len(result.Robots.Disallow)))
+	}
+	atomic.AddInt64(&harvestSitemapURLs, int64(len(result.SitemapURLs)))
+	if result.SecurityTxt != nil {
+		atomic.AddInt64(&harvestSecurityTxt, 1)
+	}
+	return result
+}
+
+// harvestGet fetches a URL and returns its body if the status was 200
+func harvestGet(rawURL string, client *http.Client, reqCfg *requestConfig, limit int64) ([]byte, http.Header, error) {
+	req, err := http.NewRequest("GET", rawURL, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create request for %s: %w", rawURL, err)
+	}
+	reqCfg.apply(req, "HyperScanner/1.4+Harvest")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("request failed for %s: %w", rawURL, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s: %w", rawURL, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("%s returned status %d", rawURL, resp.StatusCode)
+	}
+	return body, resp.Header, nil
+}
+
+// fetchSitemap downloads and parses a sitemap or sitemap index, gunzipping
+// .gz files (detected by magic bytes, as servers label them inconsistently)
+func fetchSitemap(sitemapURL string, client *http.Client, reqCfg *requestConfig) (*sitemapXML, error) {
+	body, _, err := harvestGet(sitemapURL, client, reqCfg, maxScriptSize)
+	if err != nil {
+		return nil, err
+	}
+	if len(body) >= 2 && body[0] == 0x1f && body[1] == 0x8b {
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to gunzip %s: %w", sitemapURL, err)
+		}
+		body, err = io.ReadAll(io.LimitReader(zr, 10*maxScriptSize))
+		if err != nil {
+			return nil, fmt.Errorf("failed to gunzip %s: %w", sitemapURL, err)
+		}
+	}
+	var doc sitemapXML
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse sitemap %s: %w", sitemapURL, err)
+	}
+	if doc.XMLName.Local != "urlset" && doc.XMLName.Local != "sitemapindex" {
+		return nil, fmt.Errorf("%s is not a sitemap (root element <%s>)", sitemapURL, doc.XMLName.Local)
+	}
+	return &doc, nil
+}
+
+// fetchSecurityTxt downloads a security.txt and returns its fields. HTML answers
+// (soft 404s and catch-all routes) are rejected.
+func fetchSecurityTxt(securityURL string, client *http.Client, reqCfg *requestConfig) (map[string][]string, error) {
+	body, header, err := harvestGet(securityURL, client, reqCfg, maxBodySize)
+	if err != nil {
+		return nil, err
+	}
+	if strings.Contains(strings.ToLower(header.Get("Content-Type")), "html") || bytes.Contains(bytes.ToLower(body), []byte("<html")) {
+		return nil, fmt.Errorf("%s is an HTML page, not security.txt", securityURL)
+	}
+	fields := make(map[string][]string)
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "-----") {
+			continue // Comments and PGP signature armour
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok || strings.ContainsAny(name, " \t") {
+			continue
+		}
+		fields[strings.ToLower(name)] = append(fields[strings.ToLower(name)], strings.TrimSpace(value))
+	}
+	if len(fields["contact"]) == 0 {
+		return nil, fmt.Errorf("%s has no Contact field", securityURL) // Contact is the one required field
+	}
+	return fields, nil
+}
+
+// followUpTargets returns the harvested URLs worth scanning: robots.txt paths without
+// wildcards and sitemap URLs, on the harvested origin only
+func (h *harvestResult) followUpTargets() []scanJob {
+	base, err := url.Parse(h.Origin)
+	if err != nil {
+		return nil
+	}
+	var jobs []scanJob
+	add := func(raw, source string) {
+		if len(jobs) >= maxHarvestEnqueueURL {
+			return
+		}
+		ref, err := url.Parse(raw)
+		if err != nil {
+			return
+		}
+		u := base.ResolveReference(ref)
+		if !sameOrigin(u, base) { // From crawl.go
+			return
+		}
+		jobs = append(jobs, scanJob{target: u.String(), source: source})
+	}
+	if h.Robots != nil {
+		for _, p := range append(append([]string(nil), h.Robots.Disallow...), h.Robots.Allow...) {
+			if !strings.ContainsAny(p, "*$") && p != "/" {
+				add(p, "robots")
+			}
+		}
+	}
+	for _, u := range h.SitemapURLs {
+		add(u, "sitemap")
+	}
+	return jobs
+}
+
+// jsonLine encodes the harvest as a single JSON line for harvest.jsonl
+func (h *harvestResult) jsonLine() string {
+	b, err := json.Marshal(h)
+	if err != nil {
+		return fmt.Sprintf(`{"origin":%q,"errors":[%q]}`, h.Origin, err.Error())
+	}
+	return string(b)
+}
+
+// printHarvestSummary shows what -harvest found across all hosts
+func printHarvestSummary() {
+	harvestedOriginsMutex.Lock()
+	hosts := len(harvestedOrigins)
+	harvestedOriginsMutex.Unlock()
+	if hosts == 0 {
+		return
+	}
+	fmt.Println("\nHarvest (robots.txt / sitemaps / security.txt):")
+	fmt.Printf("  %-25s : %d\n", "Hosts harvested", hosts)
+	fmt.Printf("  %-25s : %d\n", "Hosts with robots.txt", atomic.LoadInt64(&harvestHostsWithRobots))
+	fmt.Printf("  %-25s : %d\n", "Disallowed paths", atomic.LoadInt64(&harvestDisallowed))
+	fmt.Printf("  %-25s : %d\n", "Sitemap URLs", atomic.LoadInt64(&harvestSitemapURLs))
+	fmt.Printf("  %-25s : %d\n", "Hosts with security.txt", atomic.LoadInt64(&harvestSecurityTxt))
+}
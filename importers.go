@@ -0,0 +1,573 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Input formats for -input-format
+const (
+	inputFormatAuto    = "auto"    // Detect from the content (default)
+	inputFormatText    = "text"    // One target per line
+	inputFormatNmap    = "nmap"    // nmap -oX
+	inputFormatMasscan = "masscan" // masscan -oJ or -oL
+	inputFormatNaabu   = "naabu"   // naabu host:port lines or -json
+	inputFormatJSONL   = "jsonl"   // One JSON object per line with url, or host/ip (+ port, scheme)
+	inputFormatCSV     = "csv"     // CSV with a header row naming url, or host/ip (+ port, scheme)
+)
+
+var inputFormats = []string{inputFormatAuto, inputFormatText, inputFormatNmap, inputFormatMasscan, inputFormatNaabu, inputFormatJSONL, inputFormatCSV}
+
+// httpPorts are ports commonly serving HTTP(S). Port scanner results (nmap, masscan,
+// naabu) are kept when the port is listed here or the detected service is HTTP-ish.
+var httpPorts = map[int]bool{
+	80: true, 81: true, 280: true, 443: true, 591: true, 593: true, 800: true, 808: true,
+	3000: true, 3001: true, 4443: true, 4567: true, 5000: true, 5001: true, 5601: true,
+	5800: true, 7000: true, 7001: true, 7080: true, 7443: true, 8000: true, 8001: true,
+	8008: true, 8080: true, 8081: true, 8082: true, 8083: true, 8088: true, 8090: true,
+	8180: true, 8443: true, 8444: true, 8800: true, 8880: true, 8888: true, 8983: true,
+	9000: true, 9001: true, 9043: true, 9080: true, 9090: true, 9200: true, 9443: true,
+	10000: true, 10443: true, 12443: true, 16080: true, 18080: true,
+}
+
+// httpsPorts are the httpPorts assumed to speak TLS when no service name says otherwise
+var httpsPorts = map[int]bool{443: true, 4443: true, 5001: true, 7443: true, 8443: true, 8444: true, 9443: true, 10443: true, 12443: true}
+
+// importStats describes what an importer kept and skipped
+type importStats struct {
+	format  string // Format actually used (after auto-detection)
+	hosts   int    // Hosts seen in port scanner output
+	skipped int    // Open ports skipped as not HTTP-ish
+}
+
+// importTargets turns the content of an input source into targets. format may be
+// "auto"; name is only used in error messages.
+func importTargets(data []byte, format, name string) ([]string, importStats, error) {
+	if format == inputFormatAuto {
+		format = detectInputFormat(data)
+	}
+	stats := importStats{format: format}
+	var targets []string
+	var err error
+	switch format {
+	case inputFormatText:
+		targets = textTargets(data)
+	case inputFormatNmap:
+		targets, err = importNmap(data, &stats)
+	case inputFormatMasscan:
+		targets, err = importMasscan(data, &stats)
+	case inputFormatNaabu:
+		targets, err = importNaabu(data, &stats)
+	case inputFormatJSONL:
+		targets, err = importJSONL(data)
+	case inputFormatCSV:
+		targets, err = importCSV(data)
+	default:
+		return nil, stats, fmt.Errorf("invalid -input-format %q (use %s)", format, strings.Join(inputFormats, ", "))
+	}
+	if err != nil {
+		return nil, stats, fmt.Errorf("failed to read %s as %s: %w", name, format, err)
+	}
+	return targets, stats, nil
+}
+
+// readInputTargets streams the targets of an input file ("-" = stdin) in the given
+// format (or "auto") to fn and returns how many there were. Plain lists are read line
+// by line so huge inputs are never held whole; port scanner and structured formats
+// are parsed in one go.
+func readInputTargets(path, format string, fn func(target string) error) (int, importStats, error) {
+	name := path
+	var r io.Reader = os.Stdin
+	if path == stdinInput {
+		name = "stdin"
+	} else {
+		f, err := os.Open(path)
+		if err != nil {
+			return 0, importStats{}, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		defer f.Close()
+		r = f
+	}
+	br := bufio.NewReaderSize(r, 64*1024)
+	if format == inputFormatAuto {
+		head, _ := br.Peek(4096) // Short inputs return what there is; read errors surface below
+		format = detectInputFormat(head)
+	}
+
+	if format == inputFormatText {
+		n := 0
+		scanner := bufio.NewScanner(br)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				n++
+				if err := fn(line); err != nil {
+					return n, importStats{format: format}, err
+				}
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return n, importStats{format: format}, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		return n, importStats{format: format}, nil
+	}
+
+	data, err := io.ReadAll(br)
+	if err != nil {
+		return 0, importStats{}, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	targets, stats, err := importTargets(data, format, name)
+	if err != nil {
+		return 0, stats, err
+	}
+	for _, t := range targets {
+		if err := fn(t); err != nil {
+			return len(targets), stats, err
+		}
+	}
+	return len(targets), stats, nil
+}
+
+// describe summarises a structured import for the console ("" for plain lists)
+func (s importStats) describe(path string, n int) string {
+	if s.format == inputFormatText {
+		return ""
+	}
+	if s.hosts == 0 && s.skipped == 0 {
+		return fmt.Sprintf("Imported %d targets from %s (%s)", n, path, s.format)
+	}
+	return fmt.Sprintf("Imported %d targets from %s (%s: %d hosts, %d non-HTTP ports skipped)", n, path, s.format, s.hosts, s.skipped)
+}
+
+// detectInputFormat guesses the format from the first meaningful line. Anything it
+// doesn't recognise is treated as a plain target list.
+func detectInputFormat(data []byte) string {
+	head := data
+	if len(head) > 4096 {
+		head = head[:4096]
+	}
+	if bytes.Contains(head, []byte("<nmaprun")) {
+		return inputFormatNmap
+	}
+	first := ""
+	scanner := bufio.NewScanner(bytes.NewReader(head))
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			first = line
+			break
+		}
+	}
+	switch {
+	case strings.HasPrefix(first, "#masscan") || strings.HasPrefix(first, "open ") || strings.HasPrefix(first, "banner "):
+		return inputFormatMasscan
+	case looksLikeJSON(first):
+		if bytes.Contains(head, []byte(`"ports"`)) {
+			return inputFormatMasscan
+		}
+		if bytes.Contains(head, []byte(`"port"`)) && !bytes.Contains(head, []byte(`"url"`)) {
+			return inputFormatNaabu
+		}
+		return inputFormatJSONL
+	case strings.Contains(first, ","):
+		if header, err := csv.NewReader(strings.NewReader(first)).Read(); err == nil && csvColumns(header).usable() {
+			return inputFormatCSV
+		}
+	}
+	return inputFormatText
+}
+
+// looksLikeJSON reports whether a first line starts JSON input: a JSON value on its own
+// (masscan ends array elements with a comma) or the bare "[" opening masscan's array.
+// A bracketed IPv6 target such as "[2001:db8::1]:8080" is not JSON and stays text.
+func looksLikeJSON(line string) bool {
+	if line == "[" || line == "{" {
+		return true
+	}
+	if !strings.HasPrefix(line, "[") && !strings.HasPrefix(line, "{") {
+		return false
+	}
+	return json.Valid([]byte(strings.TrimSuffix(line, ",")))
+}
+
+// textTargets splits a plain list into trimmed, non-empty lines (as readTargetsFromFile does)
+func textTargets(data []byte) []string {
+	var targets []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			targets = append(targets, line)
+		}
+	}
+	return targets
+}
+
+// serviceTarget builds a target from a host, port and (possibly empty) service name,
+// or returns "" if the port doesn't look like HTTP
+func serviceTarget(host string, port int, service string, tls bool) string {
+	service = strings.ToLower(service)
+	isHTTP := strings.Contains(service, "http") || strings.Contains(service, "www")
+	if !isHTTP && !httpPorts[port] {
+		return ""
+	}
+	if !isHTTP && service != "" && service != "unknown" && service != "ssl" && service != "tls" {
+		return "" // A known non-HTTP service that happens to sit on a web port
+	}
+	scheme := "http"
+	if tls || strings.Contains(service, "https") || strings.Contains(service, "ssl") || strings.Contains(service, "tls") ||
+		(service == "" || service == "unknown") && httpsPorts[port] {
+		scheme = "https"
+	}
+	return scheme + "://" + net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+// --- nmap -oX ---
+
+type nmapRun struct {
+	Hosts []struct {
+		Status struct {
+			State string `xml:"state,attr"`
+		} `xml:"status"`
+		Addresses []struct {
+			Addr     string `xml:"addr,attr"`
+			AddrType string `xml:"addrtype,attr"`
+		} `xml:"address"`
+		Hostnames []struct {
+			Name string `xml:"name,attr"`
+			Type string `xml:"type,attr"`
+		} `xml:"hostnames>hostname"`
+		Ports []struct {
+			Protocol string `xml:"protocol,attr"`
+			PortID   int    `xml:"portid,attr"`
+			State    struct {
+				State string `xml:"state,attr"`
+			} `xml:"state"`
+			Service struct {
+				Name   string `xml:"name,attr"`
+				Tunnel string `xml:"tunnel,attr"`
+			} `xml:"service"`
+		} `xml:"ports>port"`
+	} `xml:"host"`
+}
+
+// importNmap reads open TCP ports from nmap XML. The hostname that was scanned (type
+// "user") is preferred over the address so virtual hosts keep working.
+func importNmap(data []byte, stats *importStats) ([]string, error) {
+	var run nmapRun
+	if err := xml.Unmarshal(data, &run); err != nil {
+		return nil, err
+	}
+	var targets []string
+	for _, h := range run.Hosts {
+		if h.Status.State != "" && h.Status.State != "up" {
+			continue
+		}
+		host := ""
+		for _, hn := range h.Hostnames {
+			if hn.Type == "user" {
+				host = hn.Name
+			}
+		}
+		for _, a := range h.Addresses {
+			if host == "" && (a.AddrType == "ipv4" || a.AddrType == "ipv6") {
+				host = a.Addr
+			}
+		}
+		if host == "" {
+			continue
+		}
+		stats.hosts++
+		for _, p := range h.Ports {
+			if p.Protocol != "tcp" || p.State.State != "open" {
+				continue
+			}
+			if t := serviceTarget(host, p.PortID, p.Service.Name, p.Service.Tunnel == "ssl"); t != "" {
+				targets = append(targets, t)
+			} else {
+				stats.skipped++
+			}
+		}
+	}
+	return targets, nil
+}
+
+// --- masscan -oJ / -oL ---
+
+type masscanRecord struct {
+	IP    string `json:"ip"`
+	Ports []struct {
+		Port    int    `json:"port"`
+		Proto   string `json:"proto"`
+		Status  string `json:"status"`
+		Service struct {
+			Name string `json:"name"`
+		} `json:"service"`
+	} `json:"ports"`
+}
+
+// importMasscan reads masscan JSON (-oJ, one record per line, trailing commas and all)
+// or list (-oL: "open tcp 80 1.2.3.4 1700000000") output
+func importMasscan(data []byte, stats *importStats) ([]string, error) {
+	services := make(map[string]string) // "ip:port" -> service name from banner records
+	var open []string                   // "ip:port" in input order
+	seenHost := make(map[string]bool)
+	addOpen := func(ip string, port int) {
+		key := net.JoinHostPort(ip, strconv.Itoa(port))
+		if _, ok := services[key]; !ok {
+			services[key] = ""
+			open = append(open, key)
+		}
+		seenHost[ip] = true
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(strings.TrimSpace(scanner.Text()), ",")
+		switch {
+		case strings.HasPrefix(line, "{"):
+			var rec masscanRecord
+			if err := json.Unmarshal([]byte(line), &rec); err != nil {
+				return nil, fmt.Errorf("bad record %q: %w", line, err)
+			}
+			for _, p := range rec.Ports {
+				if rec.IP == "" || (p.Proto != "" && p.Proto != "tcp") || (p.Status != "" && p.Status != "open") {
+					continue
+				}
+				addOpen(rec.IP, p.Port)
+				if p.Service.Name != "" {
+					services[net.JoinHostPort(rec.IP, strconv.Itoa(p.Port))] = p.Service.Name
+				}
+			}
+		case strings.HasPrefix(line, "open ") || strings.HasPrefix(line, "banner "):
+			// open tcp 80 1.2.3.4 1700000000 / banner tcp 80 1.2.3.4 1700000000 http ...
+			fields := strings.Fields(line)
+			if len(fields) < 4 || fields[1] != "tcp" {
+				continue
+			}
+			port, err := strconv.Atoi(fields[2])
+			if err != nil {
+				return nil, fmt.Errorf("bad port in %q", line)
+			}
+			addOpen(fields[3], port)
+			if fields[0] == "banner" && len(fields) >= 6 {
+				services[net.JoinHostPort(fields[3], fields[2])] = fields[5]
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	stats.hosts = len(seenHost)
+	var targets []string
+	for _, key := range open {
+		host, portStr, _ := net.SplitHostPort(key)
+		port, _ := strconv.Atoi(portStr)
+		if t := serviceTarget(host, port, services[key], false); t != "" {
+			targets = append(targets, t)
+		} else {
+			stats.skipped++
+		}
+	}
+	return targets, nil
+}
+
+// --- naabu ---
+
+// naabuRecord covers naabu -json across versions: "port" is a number in current
+// releases and an object in older ones
+type naabuRecord struct {
+	Host string          `json:"host"`
+	IP   string          `json:"ip"`
+	Port json.RawMessage `json:"port"`
+	TLS  bool            `json:"tls"`
+}
+
+// importNaabu reads naabu's default host:port lines or its -json output
+func importNaabu(data []byte, stats *importStats) ([]string, error) {
+	var targets []string
+	seenHost := make(map[string]bool)
+	add := func(host string, port int, tls bool) {
+		seenHost[host] = true
+		if t := serviceTarget(host, port, "", tls); t != "" {
+			targets = append(targets, t)
+		} else {
+			stats.skipped++
+		}
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "{") {
+			var rec naabuRecord
+			if err := json.Unmarshal([]byte(line), &rec); err != nil {
+				return nil, fmt.Errorf("bad record %q: %w", line, err)
+			}
+			port, tls := 0, rec.TLS
+			if err := json.Unmarshal(rec.Port, &port); err != nil {
+				var old struct {
+					Port int  `json:"Port"`
+					TLS  bool `json:"TLS"`
+				}
+				if err := json.Unmarshal(rec.Port, &old); err != nil {
+					return nil, fmt.Errorf("bad port in %q", line)
+				}
+				port, tls = old.Port, tls || old.TLS
+			}
+			host := rec.Host
+			if host == "" {
+				host = rec.IP
+			}
+			add(host, port, tls)
+			continue
+		}
+		host, portStr, err := net.SplitHostPort(line)
+		if err != nil {
+			return nil, fmt.Errorf("expected host:port, got %q", line)
+		}
+		port, err := strconv.Atoi(portStr)
+		if err != nil {
+			return nil, fmt.Errorf("bad port in %q", line)
+		}
+		add(host, port, false)
+	}
+	stats.hosts = len(seenHost)
+	return targets, scanner.Err()
+}
+
+// --- Generic JSON lines and CSV ---
+
+// recordTarget builds a target from loosely named fields: a full URL wins, otherwise
+// host (or ip) with optional port and scheme. No port filtering is applied.
+func recordTarget(url, host, port, scheme string) string {
+	if url != "" {
+		return url
+	}
+	if host == "" {
+		return ""
+	}
+	target := host
+	if port != "" && port != "0" {
+		target = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		target = "[" + host + "]"
+	}
+	if scheme = strings.ToLower(scheme); scheme == "http" || scheme == "https" {
+		target = scheme + "://" + target
+	}
+	return target
+}
+
+// importJSONL reads one JSON object per line, e.g. httpx, subfinder or dnsx -json output.
+// Recognised keys: url, host/hostname/domain/ip/input, port, scheme.
+func importJSONL(data []byte) ([]string, error) {
+	var targets []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var rec map[string]any
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		field := func(names ...string) string {
+			for _, name := range names {
+				switch v := rec[name].(type) {
+				case string:
+					if v != "" {
+						return v
+					}
+				case float64:
+					return strconv.Itoa(int(v))
+				}
+			}
+			return ""
+		}
+		if t := recordTarget(field("url"), field("host", "hostname", "domain", "ip", "input"), field("port"), field("scheme")); t != "" {
+			targets = append(targets, t)
+		}
+	}
+	return targets, scanner.Err()
+}
+
+// csvHeader maps the recognised CSV columns to their indexes (-1 if absent)
+type csvHeader struct {
+	url, host, port, scheme int
+}
+
+// csvColumns finds the recognised columns in a header row (case-insensitive)
+func csvColumns(header []string) csvHeader {
+	cols := csvHeader{url: -1, host: -1, port: -1, scheme: -1}
+	for i, name := range header {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "url", "target":
+			cols.url = i
+		case "host", "hostname", "domain", "ip", "address":
+			if cols.host < 0 {
+				cols.host = i
+			}
+		case "port":
+			cols.port = i
+		case "scheme", "protocol":
+			cols.scheme = i
+		}
+	}
+	return cols
+}
+
+func (c csvHeader) usable() bool {
+	return c.url >= 0 || c.host >= 0
+}
+
+// importCSV reads a CSV file whose header names a url/target column, or a host column
+// (host, hostname, domain, ip, address) with optional port and scheme columns
+func importCSV(data []byte) ([]string, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	header, err := r.Read()
+	if err != nil {
+		return nil, err
+	}
+	cols := csvColumns(header)
+	if !cols.usable() {
+		return nil, fmt.Errorf("header %q has no url, target, host, hostname, domain, ip or address column", strings.Join(header, ","))
+	}
+	cell := func(row []string, i int) string {
+		if i < 0 || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+	var targets []string
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			return targets, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if t := recordTarget(cell(row, cols.url), cell(row, cols.host), cell(row, cols.port), cell(row, cols.scheme)); t != "" {
+			targets = append(targets, t)
+		}
+	}
+}
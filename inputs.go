@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// stdinInput is the -i value that reads targets from standard input
+const stdinInput = "-"
+
+// inputFlag collects repeated -i/-f values. Each is a file, a glob pattern, a
+// directory or "-" for stdin.
+type inputFlag []string
+
+func (f *inputFlag) String() string { return strings.Join(*f, " ") }
+
+func (f *inputFlag) Set(v string) error {
+	*f = append(*f, v)
+	return nil
+}
+
+// inputSource is one file (or stdin) targets are read from
+type inputSource struct {
+	path  string // File path, or "-" for stdin
+	label string // Unique short name that tags results and names by_source/<label>.txt
+}
+
+// name is the source as shown to the user
+func (s inputSource) name() string {
+	if s.path == stdinInput {
+		return "stdin"
+	}
+	return s.path
+}
+
+// unsafeLabelChars are replaced in source labels so they can be used as file names
+var unsafeLabelChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// expandInputs turns the -i values into sources, in order. Patterns containing *, ?
+// or [ are globbed, directories contribute every regular, non-hidden file below them
+// (sorted), and a file reached more than once is only read once.
+func expandInputs(specs []string) ([]inputSource, error) {
+	var paths []string
+	seen := make(map[string]bool)
+	add := func(path string) {
+		key := path
+		if abs, err := filepath.Abs(path); err == nil && path != stdinInput {
+			key = abs
+		}
+		if !seen[key] {
+			seen[key] = true
+			paths = append(paths, path)
+		}
+	}
+	for _, spec := range specs {
+		switch {
+		case spec == stdinInput:
+			add(spec)
+		case strings.ContainsAny(spec, "*?["):
+			matches, err := filepath.Glob(spec)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern %q: %w", spec, err)
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("no files match %q", spec)
+			}
+			for _, m := range matches {
+				if info, err := os.Stat(m); err == nil && info.Mode().IsRegular() {
+					add(m)
+				}
+			}
+		default:
+			info, err := os.Stat(spec)
+			if err != nil {
+				return nil, fmt.Errorf("failed to open %s: %w", spec, err)
+			}
+			if !info.IsDir() {
+				add(spec)
+				continue
+			}
+			var files []string
+			err = filepath.WalkDir(spec, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if path != spec && strings.HasPrefix(d.Name(), ".") {
+					if d.IsDir() {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+				if d.Type().IsRegular() {
+					files = append(files, path)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to read directory %s: %w", spec, err)
+			}
+			if len(files) == 0 {
+				return nil, fmt.Errorf("directory %s has no input files", spec)
+			}
+			sort.Strings(files)
+			for _, f := range files {
+				add(f)
+			}
+		}
+	}
+
+	sources := make([]inputSource, len(paths))
+	used := make(map[string]bool)
+	for i, path := range paths {
+		base := "stdin"
+		if path != stdinInput {
+			base = unsafeLabelChars.ReplaceAllString(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), "_")
+			if strings.Trim(base, ".") == "" {
+				base = "input"
+			}
+		}
+		label := base
+		for n := 2; used[label]; n++ {
+			label = fmt.Sprintf("%s-%d", base, n) // a/targets.txt and b/targets.txt
+		}
+		used[label] = true
+		sources[i] = inputSource{path: path, label: label}
+	}
+	return sources, nil
+}
+
+// defaultOutputDir names the output directory after the input: <name>_output for a
+// single source, merged_output for several
+func defaultOutputDir(sources []inputSource) string {
+	if len(sources) == 1 {
+		return sources[0].label + "_output"
+	}
+	return "merged_output"
+}
+
+// estimateInputTargets guesses how many targets the sources hold from their size
+// (about one per 16 bytes; stdin, whose size is unknown, counts as a million). It only
+// sizes the -dedup disk bloom filters, which stay exact when the guess is low.
+func estimateInputTargets(sources []inputSource) int {
+	total := 0
+	for _, src := range sources {
+		if info, err := os.Stat(src.path); src.path != stdinInput && err == nil {
+			total += int(info.Size() / 16)
+		} else {
+			total += 1 << 20
+		}
+	}
+	return max(total, 1024)
+}
+
+// --- Which input source each target came from ---
+// Input targets carry their source's label through the target list (normalize.go);
+// the map only remembers the label of failed targets, so a re-scan keeps it.
+var inputLabels []string                         // Labels of all sources, in order
+var inputSourceTags = make(map[string]string)    // Failed target (in claimTarget form) -> label of its source
+var inputSourceStats = make(map[string][2]int64) // Label -> {results, live}
+var inputSourceMutex sync.Mutex
+
+// tagInputSource records the source of a target queued for a possible re-scan
+func tagInputSource(target, label string) {
+	if len(inputLabels) < 2 || label == "" {
+		return
+	}
+	inputSourceMutex.Lock()
+	inputSourceTags[targetKey(target)] = label // From queue.go
+	inputSourceMutex.Unlock()
+}
+
+// inputSourceOf returns the label of the source a failed target came from
+func inputSourceOf(target string) string {
+	if len(inputLabels) == 1 {
+		return inputLabels[0]
+	}
+	inputSourceMutex.Lock()
+	defer inputSourceMutex.Unlock()
+	return inputSourceTags[targetKey(target)]
+}
+
+// describeInputSource is appended to log lines when there are several sources
+func describeInputSource(label string) string {
+	if len(inputLabels) < 2 || label == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (from %s)", label)
+}
+
+// recordInputResult counts a result under its source. A re-scan success only adds
+// to the live count, since the failed initial attempt was already counted.
+func recordInputResult(label string, live, isRescan bool) {
+	if label == "" {
+		return
+	}
+	inputSourceMutex.Lock()
+	defer inputSourceMutex.Unlock()
+	s := inputSourceStats[label]
+	if !isRescan {
+		s[0]++
+	}
+	if live {
+		s[1]++
+	}
+	inputSourceStats[label] = s
+}
+
+// printInputSummary shows live/failed counts per source when several were merged
+func printInputSummary() {
+	if len(inputLabels) < 2 {
+		return
+	}
+	inputSourceMutex.Lock()
+	defer inputSourceMutex.Unlock()
+	fmt.Println("\nInput Sources:")
+	for _, label := range inputLabels {
+		s := inputSourceStats[label]
+		fmt.Printf("  %-25s : %d results, %s%d live%s, %s%d failed%s\n", label, s[0],
+			ColorSuccess, s[1], ColorReset, ColorError, s[0]-s[1], ColorReset)
+	}
+}
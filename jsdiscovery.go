@@ -0,0 +1,235 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	maxScriptSize     = 2 << 20 // Larger scripts are truncated (bundles can be huge)
+	maxScriptsPerPage = 25      // Script tags beyond this are ignored
+	minSecretEntropy  = 3.5     // Shannon entropy (bits/char) a generic secret must reach
+)
+
+// scriptTagRegex finds <script> opening tags; the src is read with htmlAttr (favicon.go)
+var scriptTagRegex = regexp.MustCompile(`(?is)<script\b[^>]*>`)
+
+// jsEndpointRegex finds quoted absolute URLs, root-relative and dot-relative paths, and
+// bare relative paths ending in a typical server-side extension (LinkFinder-style)
+var jsEndpointRegex = regexp.MustCompile("[\"'`]" +
+	`((?:https?:)?//[a-zA-Z0-9.-]+(?::\d+)?(?:/[^"'` + "`" + `\s<>]*)?` +
+	`|/[a-zA-Z0-9_\-.~%]+(?:/[a-zA-Z0-9_\-.~%{}:]*)*(?:\?[^"'` + "`" + `\s<>]*)?` +
+	`|\.{1,2}/[^"'` + "`" + `\s<>]+` +
+	`|[a-zA-Z0-9_\-]+(?:/[a-zA-Z0-9_\-.]+)+\.(?:php|aspx?|jsp|json|action|do|xml|txt|cgi)(?:\?[^"'` + "`" + `\s<>]*)?)` +
+	"[\"'`]")
+
+// jsSecretRule is a named pattern for credentials found in JavaScript
+type jsSecretRule struct {
+	name    string
+	pattern *regexp.Regexp
+	entropy bool // Require minSecretEntropy on the captured value (for generic patterns)
+}
+
+// jsSecretRules are checked against every script; group 1 is the secret when present
+var jsSecretRules = []jsSecretRule{
+	{name: "aws-access-key", pattern: regexp.MustCompile(`\b((?:AKIA|ASIA)[0-9A-Z]{16})\b`)},
+	{name: "google-api-key", pattern: regexp.MustCompile(`\b(AIza[0-9A-Za-z_-]{35})\b`)},
+	{name: "github-token", pattern: regexp.MustCompile(`\b(gh[pousr]_[A-Za-z0-9]{36,})\b`)},
+	{name: "slack-token", pattern: regexp.MustCompile(`\b(xox[abposr]-[0-9A-Za-z-]{10,})\b`)},
+	{name: "stripe-secret-key", pattern: regexp.MustCompile(`\b((?:sk|rk)_live_[0-9a-zA-Z]{24,})\b`)},
+	{name: "private-key", pattern: regexp.MustCompile(`-----BEGIN (?:RSA |EC |DSA |OPENSSH )?PRIVATE KEY-----`)},
+	{name: "generic-secret", entropy: true, pattern: regexp.MustCompile(
+		`(?i)(?:api[_-]?key|api[_-]?secret|secret[_-]?key|client[_-]?secret|access[_-]?token|auth[_-]?token|password|passwd)["']?\s*[:=]\s*["']([A-Za-z0-9_\-+/=.]{16,})["']`)},
+}
+
+// jsStaticExtensions are endpoints not worth scanning as new targets
+var jsStaticExtensions = map[string]bool{
+	".js": true, ".css": true, ".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".svg": true,
+	".ico": true, ".woff": true, ".woff2": true, ".ttf": true, ".eot": true, ".map": true, ".webp": true, ".mp4": true,
+}
+
+// jsEndpoint is an endpoint found in a script, resolved against the page URL
+type jsEndpoint struct {
+	raw      string // As written in the script
+	resolved string // Absolute URL
+	script   string // Script (or page, for inline scripts) it was found in
+}
+
+// jsSecret is a credential-looking value found in a script
+type jsSecret struct {
+	rule   string
+	value  string
+	script string
+}
+
+// jsResult holds what JavaScript discovery found for one target
+type jsResult struct {
+	target    string
+	scripts   []string // Same-origin scripts that were downloaded
+	endpoints []jsEndpoint
+	hosts     []string // Hostnames referenced by absolute URLs
+	secrets   []jsSecret
+	errs      []error // Failed script downloads
+}
+
+// checkJS downloads the page's same-origin scripts and mines them, together with the
+// page's inline scripts, for endpoints, hostnames and secrets. Only HTML pages are looked at.
+func checkJS(target string, mainResp *probeResponse, client *http.Client, reqCfg *requestConfig) jsResult {
+	result := jsResult{target: target}
+	if !looksLikeHTML(mainResp) {
+		return result
+	}
+	pageURL := mainResp.url
+
+	seenEndpoint := make(map[string]bool)
+	seenHost := make(map[string]bool)
+	seenSecret := make(map[string]bool)
+	analyze := func(source string, base *url.URL, code string) {
+		for _, m := range jsEndpointRegex.FindAllStringSubmatch(code, -1) {
+			raw := m[1]
+			if len(raw) < 2 || seenEndpoint[raw] {
+				continue
+			}
+			ref, err := url.Parse(raw)
+			if err != nil {
+				continue
+			}
+			seenEndpoint[raw] = true
+			resolved := base.ResolveReference(ref)
+			result.endpoints = append(result.endpoints, jsEndpoint{raw: raw, resolved: resolved.String(), script: source})
+			if ref.Host != "" && resolved.Hostname() != "" && !seenHost[resolved.Hostname()] {
+				seenHost[resolved.Hostname()] = true
+				result.hosts = append(result.hosts, resolved.Hostname())
+			}
+		}
+		for _, rule := range jsSecretRules {
+			for _, m := range rule.pattern.FindAllStringSubmatch(code, -1) {
+				value := m[0]
+				if len(m) > 1 {
+					value = m[1]
+				}
+				if seenSecret[rule.name+value] || (rule.entropy && shannonEntropy(value) < minSecretEntropy) {
+					continue
+				}
+				seenSecret[rule.name+value] = true
+				result.secrets = append(result.secrets, jsSecret{rule: rule.name, value: value, script: source})
+			}
+		}
+	}
+
+	// Inline scripts (and anything else quoted in the page) are analysed as part of the page
+	analyze(pageURL.String(), pageURL, string(mainResp.body))
+
+	scripts := 0
+	for _, tag := range scriptTagRegex.FindAllString(string(mainResp.body), -1) {
+		src := htmlAttr(tag, "src") // From favicon.go
+		if src == "" {
+			continue
+		}
+		ref, err := url.Parse(src)
+		if err != nil {
+			continue
+		}
+		scriptURL := pageURL.ResolveReference(ref)
+		if scriptURL.Host != pageURL.Host || (scriptURL.Scheme != "http" && scriptURL.Scheme != "https") {
+			continue // Same origin only: third-party CDNs are out of scope
+		}
+		if scripts++; scripts > maxScriptsPerPage {
+			break
+		}
+		code, err := fetchScript(scriptURL.String(), client, reqCfg)
+		if err != nil {
+			result.errs = append(result.errs, err)
+			continue
+		}
+		result.scripts = append(result.scripts, scriptURL.String())
+		analyze(scriptURL.String(), scriptURL, code)
+	}
+	return result
+}
+
+// fetchScript downloads a script, truncated at maxScriptSize
+func fetchScript(scriptURL string, client *http.Client, reqCfg *requestConfig) (string, error) {
+	req, err := http.NewRequest("GET", scriptURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create script request for %s: %w", scriptURL, err)
+	}
+	reqCfg.apply(req, "HyperScanner/1.4+JSCheck")
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("script request failed for %s: %w", scriptURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, maxBodySize))
+		return "", fmt.Errorf("script %s returned status %d", scriptURL, resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxScriptSize))
+	if err != nil {
+		return "", fmt.Errorf("failed to read script %s: %w", scriptURL, err)
+	}
+	return string(body), nil
+}
+
+// looksLikeHTML reports whether a response is an HTML page
+func looksLikeHTML(resp *probeResponse) bool {
+	if ct := strings.ToLower(resp.header.Get("Content-Type")); ct != "" {
+		return strings.Contains(ct, "html")
+	}
+	head := strings.ToLower(string(resp.body[:min(len(resp.body), 512)]))
+	return strings.Contains(head, "<html") || strings.Contains(head, "<!doctype html")
+}
+
+// followUpTargets returns the endpoints worth scanning as new targets: same host as
+// the page, http(s), and not static assets. Query strings are kept; fragments are not.
+func (r *jsResult) followUpTargets(page *url.URL) []jsEndpoint {
+	var out []jsEndpoint
+	seen := make(map[string]bool)
+	for _, ep := range r.endpoints {
+		u, err := url.Parse(ep.resolved)
+		if err != nil || u.Host != page.Host || (u.Scheme != "http" && u.Scheme != "https") {
+			continue
+		}
+		if strings.Contains(u.Path, "{") || jsStaticExtensions[strings.ToLower(path.Ext(u.Path))] {
+			continue // Templated paths (/users/{id}) and assets aren't real targets
+		}
+		u.Fragment = ""
+		if s := u.String(); !seen[s] {
+			seen[s] = true
+			out = append(out, jsEndpoint{raw: ep.raw, resolved: s, script: ep.script})
+		}
+	}
+	return out
+}
+
+// shannonEntropy returns the entropy of s in bits per character
+func shannonEntropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	counts := make(map[rune]int)
+	for _, r := range s {
+		counts[r]++
+	}
+	n := float64(len([]rune(s)))
+	var h float64
+	for _, c := range counts {
+		p := float64(c) / n
+		h -= p * math.Log2(p)
+	}
+	return h
+}
+
+// sortedEndpoints returns the endpoints ordered by resolved URL, for stable output
+func (r *jsResult) sortedEndpoints() []jsEndpoint {
+	eps := append([]jsEndpoint(nil), r.endpoints...)
+	sort.Slice(eps, func(i, j int) bool { return eps[i].resolved < eps[j].resolved })
+	return eps
+}
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"flag"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -17,22 +18,21 @@ import (
 )
 
 // runScanPhase executes either the initial scan or the re-scan
-// Added corsCheck flag
 func runScanPhase(
-	targets []string,
+	targets *targetList,
 	description string,
 	isRescan bool,
 	client *http.Client,
 	workersCount int,
 	outputDir string,
 	quiet bool,
-	corsCheck bool, // <-- Added parameter
+	opts *scanOptions,
 	successfulScans *int64,
 	failedScans *int64,
 	statusCounts map[int]int64,
 	statusCountsMutex *sync.Mutex,
 ) { // [source: 29]
-	totalScanTargets := len(targets)
+	totalScanTargets := targets.len()
 	if totalScanTargets == 0 {
 		if isRescan {
 			fmt.Printf("%s[*] No targets needed re-scanning.%s\n", ColorInfo, ColorReset)
@@ -42,37 +42,38 @@ func runScanPhase(
 
 	fmt.Printf("\n%s[*] Starting %s for %d targets...%s\n", ColorInfo, description, totalScanTargets, ColorReset)
 
-	// Setup channels and waitgroup for this scan phase
-	jobs := make(chan string, workersCount)
+	// Progress bar for this phase (createProgressBar is in ui.go); discovered targets grow its max
+	bar := createProgressBar(totalScanTargets, fmt.Sprintf("%s[*] %s%s", ColorInfo, description, ColorReset))
+
+	// Setup the job queue and waitgroups for this scan phase (jobQueue is in queue.go)
+	source := "input"
+	if isRescan {
+		source = "rescan"
+	}
+	// Input targets were claimed while they were read (normalize.go), so their
+	// discoveries aren't queued again
+	queue := newJobQueue(targets, source, bar, workersCount, opts.scope)
 	results := make(chan scanResult, workersCount*2) // Increase buffer slightly for results+cors
 	var wg sync.WaitGroup                            // For workers in this phase
 	var resultWg sync.WaitGroup                      // For result processor in this phase
 
 	// Start workers for this phase (worker function is in scanner.go)
-	// Pass corsCheck flag to worker
 	for w := 1; w <= workersCount; w++ {
 		wg.Add(1)
-		// Pass corsCheck flag here
-		go worker(w, &wg, client, jobs, results, isRescan, corsCheck) // <-- Pass corsCheck
+		go worker(w, &wg, client, queue, results, isRescan, opts)
 	} // [source: 30]
 
-	// Progress bar for this phase (createProgressBar is in ui.go)
-	bar := createProgressBar(totalScanTargets, fmt.Sprintf("%s[*] %s%s", ColorInfo, description, ColorReset))
-
 	// Start results processor for this phase (processResults is in results.go)
 	resultWg.Add(1)
-	// Pass corsCheck flag down to results processor to potentially influence output
-	go processResults(results, &resultWg, bar, quiet, corsCheck, outputDir,
+	// Pass options down to results processor so check-specific output is handled
+	go processResults(results, &resultWg, bar, quiet, opts, outputDir,
 		successfulScans, failedScans,
 		statusCounts, statusCountsMutex,
 		!isRescan, // trackFailures is true only for initial scan (!isRescan)
 	)
 
-	// Send jobs for this phase
-	for _, target := range targets {
-		jobs <- target
-	}
-	close(jobs) // Done sending jobs for this phase
+	// The queue closes itself once every job, including discovered ones, is done
+	queue.closeWhenDone()
 
 	// Wait for completion of this phase
 	wg.Wait()       // Wait for workers
@@ -91,8 +92,17 @@ func main() {
 	printBanner() // From ui.go
 
 	// --- Command Line Flags ---
-	targetInput := flag.String("i", "", "Input file containing IPs, Domains, or URLs (one per line)")
-	fileInput := flag.String("f", "", "Alias for -i (optional)")
+	var inputs inputFlag
+	flag.Var(&inputs, "i", "Input file, glob, directory or '-' for stdin (repeatable; all inputs are merged)")
+	flag.Var(&inputs, "f", "Alias for -i")
+	outputFlag := flag.String("o", "", "Output directory (default: <input name>_output, or merged_output for several inputs)")
+	overwrite := flag.Bool("overwrite", false, "Delete previous results in the output directory before scanning")
+	appendOutput := flag.Bool("append", false, "Add to previous results in the output directory instead of refusing to reuse it")
+	timestamped := flag.Bool("timestamped", false, "Write to a new <dir>_YYYYMMDD_HHMMSS directory every run")
+	htmlReport := flag.String("html", "", "Write a self-contained HTML report (summary, chart, findings, sortable results) to this file")
+	csvExport := flag.String("csv", "", "Append every result to this CSV file as it arrives")
+	mdExport := flag.String("md", "", "Append every result to this Markdown table as it arrives")
+	exportColumnSpec := flag.String("columns", defaultExportColumns, "Columns for -csv/-md, comma-separated: "+exportColumnNames())
 	helpFlag := flag.Bool("h", false, "Show help")
 	defaultWorkers := runtime.NumCPU()
 	if defaultWorkers < 4 {
@@ -102,6 +112,47 @@ func main() {
 	timeout := flag.Duration("t", 5*time.Second, "HTTP request timeout (e.g., 3s, 10s)")
 	quiet := flag.Bool("q", false, "Quiet mode: suppress individual results, show only progress and summary")
 	corsCheck := flag.Bool("cors", false, "Perform a basic CORS vulnerability check on successful targets") // <-- Added CORS flag
+	faviconCheck := flag.Bool("favicon", false, "Fetch live targets' favicons and record mmh3/MD5/SHA-256 hashes")
+	wafCheck := flag.Bool("waf", false, "Detect WAFs/CDNs from response headers, cookies and block pages")
+	wafProbe := flag.Bool("waf-probe", false, "Also send a benign but suspicious-looking probe request to detect WAFs (implies -waf)")
+	crlfCheck := flag.Bool("crlf", false, "Probe the path and common query parameters for CRLF/header injection")
+	cacheCheck := flag.Bool("cache-poison", false, "Detect caches and test commonly unkeyed headers for cache poisoning (uses a cache buster)")
+	takeoverCheck := flag.Bool("takeover", false, "Check for subdomain takeover via CNAME chains and service fingerprints")
+	resolvers := flag.String("resolvers", "", "DNS servers (ip[:port]), comma-separated or a file with one per line; used round-robin for all lookups")
+	slowThreshold := flag.Duration("slow", 0, "Flag hosts whose total request time exceeds this duration (e.g. 2s; 0 disables)")
+	method := flag.String("X", "", "HTTP method for the primary request (default GET, or POST when -d is given)")
+	var headers headerFlag
+	flag.Var(&headers, "H", "Custom request header 'Name: value' (repeatable)")
+	data := flag.String("d", "", "Request body, or @file to read it from a file")
+	cookie := flag.String("cookie", "", "Cookie header value to send, e.g. 'session=abc; theme=dark'")
+	userAgent := flag.String("ua", "", "User-Agent to send, or 'random' to pick from a built-in browser list per request")
+	matchString := flag.String("match-string", "", "Only report live hosts whose body contains this string")
+	matchRegex := flag.String("match-regex", "", "Only report live hosts whose body matches this regex")
+	matchCode := flag.String("match-code", "", "Only report these status codes (comma-separated)")
+	filterCode := flag.String("filter-code", "", "Don't report these status codes (comma-separated)")
+	filterSize := flag.String("filter-size", "", "Don't report responses with these body sizes in bytes (comma-separated)")
+	filterWords := flag.String("filter-words", "", "Don't report responses with these body word counts (comma-separated)")
+	filterTitle := flag.String("filter-title", "", "Don't report pages whose <title> contains this text (case-insensitive)")
+	var extractValues extractFlag
+	flag.Var(&extractValues, "extract", "Extract data from responses: built-ins ("+builtinExtractNames()+", all) or name:regex (repeatable)")
+	jsCheck := flag.Bool("js", false, "Download same-origin scripts and extract endpoints, hostnames and secrets")
+	jsEnqueue := flag.Bool("js-enqueue", false, "Scan same-host endpoints found by -js as new targets (implies -js)")
+	jsDepth := flag.Int("js-depth", 1, "How many levels deep -js-enqueue follows discovered endpoints")
+	crawl := flag.Bool("crawl", false, "Follow same-origin links (a, area, form, link, script, iframe) from live HTML pages")
+	crawlDepth := flag.Int("crawl-depth", 2, "How many links deep -crawl goes from each input target")
+	crawlMax := flag.Int("crawl-max", 100, "Maximum pages -crawl queues per host")
+	robots := flag.Bool("robots", false, "Don't crawl paths disallowed by robots.txt (User-agent: *)")
+	harvest := flag.Bool("harvest", false, "Fetch and parse robots.txt, sitemaps and security.txt once per live host (harvest.jsonl)")
+	harvestEnqueue := flag.Bool("harvest-enqueue", false, "Scan harvested robots.txt paths and sitemap URLs as new targets (implies -harvest)")
+	templatesSpec := flag.String("templates", "", "Comma-separated YAML template directories/files, or 'builtin' for the bundled checks")
+	rawFile := flag.String("raw", "", "Raw HTTP request file with {{Host}}/{{Path}}/... placeholders, sent byte for byte as the primary probe")
+	retryAll := flag.Bool("retry-all", false, "Offer to re-scan every failed target, including non-retryable errors (NXDOMAIN, refused, bad cert)")
+	dnsRecords := flag.Bool("dns", false, "Record A/AAAA/CNAME answers per target (always on with -resolvers)")
+	inputFormat := flag.String("input-format", inputFormatAuto, "Input format: "+strings.Join(inputFormats, ", ")+" (auto detects nmap XML, masscan, naabu JSON, JSON lines and CSV)")
+	normalize := flag.Bool("normalize", true, "Normalize input targets (scheme, lowercase host, punycode, default ports, paths) before de-duplicating")
+	dedupMode := flag.String("dedup", dedupMemory, "De-duplicate input targets: memory, disk (for huge inputs) or off")
+	scopeFile := flag.String("scope", "", "Scope file: hosts, *.wildcards, CIDRs, re:<regex>; '!' prefix excludes (out_of_scope.txt)")
+	methodsCheck := flag.Bool("methods", false, "Enumerate risky HTTP methods (TRACE/XST, PUT/DELETE on a canary path, method override headers)")
 
 	flag.Parse() // [source: 32]
 
@@ -112,67 +163,348 @@ func main() {
 		fmt.Println("If no scheme (http:// or https://) is provided for a domain/IP, http:// is assumed.")
 		fmt.Println("Offers an option to re-scan failed targets and check for CORS misconfigurations.")
 		fmt.Println("\nOptions:")
-		fmt.Println("  -i <file>     Input file with targets (IPs/Domains/URLs), one per line (required if -f not used)")
-		fmt.Println("  -f <file>     Alias for -i")
+		fmt.Println("  -i <input>    Input file with targets (IPs/Domains/URLs), one per line. Repeatable; also accepts")
+		fmt.Println("                globs ('scans/*.txt'), directories and '-' for stdin, merged and de-duplicated")
+		fmt.Println("                Results are tagged with their source and split into by_source/<name>.txt")
+		fmt.Println("  -f <input>    Alias for -i (both can be mixed)")
+		fmt.Println("  -o <dir>      Output directory (default: <input name>_output, or merged_output for several inputs)")
+		fmt.Println("                A non-empty directory needs one of:")
+		fmt.Println("  -overwrite    Delete the previous results first (only hxscanner's own files, in directories with a run.json)")
+		fmt.Println("  -append       Keep the previous results and add to them")
+		fmt.Println("  -timestamped  Use a fresh <dir>_YYYYMMDD_HHMMSS directory instead")
+		fmt.Println("  -html <file>  Write a self-contained HTML report: summary, status code chart, findings by severity,")
+		fmt.Println("                and a sortable/filterable table of every result (title, tech, timings)")
+		fmt.Println("  -csv <file>   Write results to a CSV file as they arrive (usable while the scan runs)")
+		fmt.Println("  -md <file>    Write results to a Markdown table as they arrive")
+		fmt.Println("  -columns <list> Columns for -csv/-md (default: " + defaultExportColumns + ")")
+		fmt.Println("                Available: " + exportColumnNames())
 		fmt.Println("  -w <number>   Number of concurrent scanning workers (default: number of CPU cores)") // [source: 33]
 		fmt.Println("  -t <duration> HTTP request timeout (default: 5s)")                                   // [source: 33]
 		fmt.Println("  -q            Quiet mode: suppress individual results (except errors/warnings)")     // [source: 33]
 		fmt.Println("  --cors        Perform basic CORS vulnerability check on successful targets")         // <-- Added help text
-		fmt.Println("  -h            Show this help message")                                               // [source: 33]
+		fmt.Println("  -favicon      Hash live targets' favicons (mmh3/MD5/SHA-256) and group hosts by hash")
+		fmt.Println("  -waf          Detect WAFs/CDNs from headers, cookies and block pages; block pages don't count as live")
+		fmt.Println("                and hosts that throttle (429/Retry-After) are backed off")
+		fmt.Println("  -waf-probe    Also send a suspicious-looking probe request to detect WAFs (implies -waf)")
+		fmt.Println("  -methods      Enumerate risky HTTP methods (TRACE/XST, PUT/DELETE on a canary path, overrides)")
+		fmt.Println("  -crlf         Probe the path and common query parameters for CRLF/header injection")
+		fmt.Println("  -cache-poison Detect caches and test unkeyed headers for cache poisoning (with a cache buster)")
+		fmt.Println("  -takeover     Check for subdomain takeover via CNAME chains and service fingerprints")
+		fmt.Println("  -resolvers <list|file> DNS servers (ip[:port]) used round-robin for all lookups (default: system)")
+		fmt.Println("  -dns          Record A/AAAA/CNAME answers per target (always on with -resolvers)")
+		fmt.Println("  -retry-all    Re-scan all failures, including non-retryable ones (NXDOMAIN, refused, bad cert)")
+		fmt.Println("  -slow <duration> Flag hosts slower than this in total request time (written to slow_hosts.txt)")
+		fmt.Println("  -X <method>   HTTP method for the primary request (default GET, or POST with -d)")
+		fmt.Println("  -H 'Name: v'  Custom request header (repeatable); also sent with the CORS check")
+		fmt.Println("  -d <body>     Request body, or -d @file to read it from a file")
+		fmt.Println("  -cookie <c>   Cookie header to send (also sent with the CORS check)")
+		fmt.Println("  -ua <agent>   User-Agent to send, or 'random' for a random browser UA per request")
+		fmt.Println("  -match-string <s>  Only report live hosts whose body contains <s>")
+		fmt.Println("  -match-regex <re>  Only report live hosts whose body matches <re>")
+		fmt.Println("  -match-code <list> Only report these status codes, e.g. 200,302")
+		fmt.Println("  -filter-code <list>  Don't report these status codes, e.g. 404,429")
+		fmt.Println("  -filter-size <list>  Don't report these body sizes in bytes")
+		fmt.Println("  -filter-words <list> Don't report these body word counts")
+		fmt.Println("  -filter-title <s>    Don't report pages whose title contains <s> (case-insensitive)")
+		fmt.Println("                Filtered results are logged and counted but kept out of the terminal and status files;")
+		fmt.Println("                every enabled check still runs on them and reports its findings")
+		fmt.Println("  -extract <rules> Extract data from response headers/bodies into extracted/<rule>.txt (repeatable)")
+		fmt.Println("                Built-ins: " + builtinExtractNames() + ", all. Custom: -extract 'name:regex' (group 1 if present)")
+		fmt.Println("  -js           Mine same-origin JavaScript for endpoints, hostnames and secrets (js_endpoints.txt, js_secrets.txt)")
+		fmt.Println("  -js-enqueue   Also scan same-host JS endpoints as new targets (implies -js)")
+		fmt.Println("  -js-depth <n> Discovery depth for -js-enqueue (default: 1, i.e. endpoints of input targets only)")
+		fmt.Println("  -crawl        Follow same-origin links from live HTML pages (results in crawled.txt)")
+		fmt.Println("  -crawl-depth <n> Link depth for -crawl (default: 2)")
+		fmt.Println("  -crawl-max <n>   Maximum pages -crawl queues per host (default: 100)")
+		fmt.Println("  -robots       Respect robots.txt Disallow rules while crawling")
+		fmt.Println("  -harvest      Parse robots.txt, sitemap.xml (indexes, gzip) and security.txt per live host (harvest.jsonl)")
+		fmt.Println("  -harvest-enqueue Also scan disallowed/allowed paths and sitemap URLs as new targets (implies -harvest)")
+		fmt.Println("  -templates <list> Run YAML template checks from directories/files (comma-separated; 'builtin' = bundled CORS checks)")
+		fmt.Println("  -input-format <fmt> Input format: auto (default), text, nmap (-oX), masscan (-oJ/-oL), naabu, jsonl, csv")
+		fmt.Println("                Port scan results keep open HTTP-ish ports; the service name (or port) picks http/https")
+		fmt.Println("  -normalize    Rewrite input targets to canonical URLs before de-duplication (default: true; -normalize=false keeps lines as written)")
+		fmt.Println("  -dedup <mode> De-duplicate input targets: memory (default), disk (bloom filter + temp files, for huge inputs) or off")
+		fmt.Println("  -scope <file> Only touch targets matching the scope file, enforced on input, follow-ups and every request:")
+		fmt.Println("                example.com, *.example.com, 10.0.0.0/8, re:<regex on URL>; prefix '!' to exclude")
+		fmt.Println("                Anything out of scope is logged to out_of_scope.txt with the reason")
+		fmt.Println("  -raw <file>   Send a raw (Burp-style) request file as the primary probe. Placeholders:")
+		fmt.Println("                {{Host}} {{Hostname}} {{Port}} {{Path}} {{Scheme}} {{BaseURL}} {{RandStr}} {{RandInt}}")
+		fmt.Println("                {{ContentLength}} (rendered body length; a literal Content-Length is sent as-is)")
+		fmt.Println("  -h            Show this help message") // [source: 33]
 		os.Exit(0)
 	}
 
 	// --- Input File Validation ---
-	if *targetInput == "" && *fileInput == "" {
+	if len(inputs) == 0 {
 		fmt.Printf("%sError: No input file provided. Use -i <file> or -f <file>%s\n", ColorError, ColorReset) // [source: 34]
 		flag.Usage()
 		os.Exit(1)
 	}
-	targetListPath := *targetInput
-	if *fileInput != "" {
-		if targetListPath != "" && targetListPath != *fileInput {
-			fmt.Printf("%sWarning: Both -i and -f provided, using -f value: %s%s\n", ColorWarning, *fileInput, ColorReset)
+	outputMode := outputModeNew
+	modeCount := 0
+	for mode, set := range map[string]bool{outputModeOverwrite: *overwrite, outputModeAppend: *appendOutput, outputModeTimestamped: *timestamped} {
+		if set {
+			outputMode = mode
+			modeCount++
 		}
-		targetListPath = *fileInput
+	}
+	if modeCount > 1 {
+		fmt.Printf("%sError: -overwrite, -append and -timestamped can't be combined%s\n", ColorError, ColorReset)
+		os.Exit(1)
+	}
+	sources, err := expandInputs(inputs) // From inputs.go
+	if err != nil {
+		fmt.Printf("%sError: %v%s\n", ColorError, err, ColorReset)
+		os.Exit(1)
+	}
+	inputDescription := sources[0].name()
+	if len(sources) > 1 {
+		inputDescription = fmt.Sprintf("%d inputs", len(sources))
+	}
+	for _, src := range sources {
+		inputLabels = append(inputLabels, src.label)
 	}
 
-	// --- Read all targets into memory first ---
-	fmt.Printf("%s[*] Reading targets from %s...%s\n", ColorInfo, targetListPath, ColorReset)
-	initialTargets, err := readTargetsFromFile(targetListPath) // From utils.go
+	// --- Read targets, normalizing and de-duplicating them as they stream in (normalize.go) ---
+	fmt.Printf("%s[*] Reading targets from %s...%s\n", ColorInfo, inputDescription, ColorReset)
+	prep, err := newTargetPreparer(*normalize, *dedupMode, estimateInputTargets(sources))
 	if err != nil {
-		fmt.Printf("%sError reading input file %s: %v%s\n", ColorError, targetListPath, err, ColorReset)
+		fmt.Printf("%sError: %v%s\n", ColorError, err, ColorReset)
 		os.Exit(1)
 	}
-	totalTargets := len(initialTargets)
-	if totalTargets == 0 {
-		fmt.Printf("%sWarning: Input file %s appears to be empty or contains no valid targets.%s\n", ColorWarning, targetListPath, ColorReset)
+	for _, src := range sources {
+		n, imported, err := readInputTargets(src.path, *inputFormat, func(t string) error { // From importers.go
+			return prep.add(t, src.label)
+		})
+		if err != nil {
+			fmt.Printf("%sError reading input file %s: %v%s\n", ColorError, src.name(), err, ColorReset)
+			os.Exit(1)
+		}
+		if msg := imported.describe(src.name(), n); msg != "" {
+			fmt.Printf("%s[*] %s%s\n", ColorInfo, msg, ColorReset)
+		} else if len(sources) > 1 {
+			fmt.Printf("%s    %s: %d targets (as %s)%s\n", ColorInfo, src.name(), n, src.label, ColorReset)
+		}
+		if n == 0 && len(sources) > 1 {
+			fmt.Printf("%sWarning: Input file %s appears to be empty or contains no valid targets.%s\n", ColorWarning, src.name(), ColorReset)
+		}
+	}
+	prep.close()
+	initialTargets := prep.targets
+	inputDedupStats = prep.stats
+	defer initialTargets.close()
+	if initialTargets.len() == 0 {
+		if len(sources) > 1 {
+			fmt.Printf("%sWarning: None of the %d inputs contain valid targets.%s\n", ColorWarning, len(sources), ColorReset)
+		} else {
+			fmt.Printf("%sWarning: Input file %s appears to be empty or contains no valid targets.%s\n", ColorWarning, inputDescription, ColorReset)
+		}
 		os.Exit(0)
 	}
+	if s := inputDedupStats; s.rewritten > 0 || s.collapsed > 0 {
+		fmt.Printf("%s[*] Normalized %d input lines: %d rewritten, %d duplicates collapsed.%s\n", ColorInfo, s.inputs, s.rewritten, s.collapsed, ColorReset)
+	}
+	totalTargets := initialTargets.len()
 	fmt.Printf("%s[*] Found %d targets to scan.%s\n", ColorInfo, totalTargets, ColorReset)
 
-	// --- Output Directory Setup ---
-	outputDir := strings.TrimSuffix(filepath.Base(targetListPath), filepath.Ext(targetListPath)) + "_output"
-	err = createOutputStructure(outputDir) // From output.go
+	// --- CSV/Markdown Export Columns (the files are created with the output directory) ---
+	var exportCols []exportColumn
+	if *csvExport != "" || *mdExport != "" {
+		exportCols, err = parseExportColumns(*exportColumnSpec) // From export.go
+		if err != nil {
+			fmt.Printf("%sError: %v%s\n", ColorError, err, ColorReset)
+			os.Exit(1)
+		}
+	}
+
+	// --- Scope Rules (applied to the input once the output directory exists) ---
+	var scope *scopeRules
+	if *scopeFile != "" {
+		scope, err = loadScope(*scopeFile) // From scope.go
+		if err != nil {
+			fmt.Printf("%sError: %v%s\n", ColorError, err, ColorReset)
+			os.Exit(1)
+		}
+	}
+
+	// --- Request Customisation ---
+	reqCfg, err := newRequestConfig(*method, headers, *data, *cookie, *userAgent) // From request.go
 	if err != nil {
-		fmt.Printf("%sError creating output structure in %s: %v%s\n", ColorError, outputDir, err, ColorReset)
+		fmt.Printf("%sError: %v%s\n", ColorError, err, ColorReset)
 		os.Exit(1)
 	}
-	fmt.Printf("%s[*] Output will be saved to: %s%s%s\n", ColorInfo, ColorAccent, outputDir, ColorReset) // [source: 35]
+
+	// --- DNS Resolver Setup ---
+	resolverList, err := parseResolverList(*resolvers)
+	if err != nil {
+		fmt.Printf("%sError reading resolvers from %s: %v%s\n", ColorError, *resolvers, err, ColorReset)
+		os.Exit(1)
+	}
+	dnsResolver := newDNSClient(resolverList, *timeout) // From dns.go
+	var transportResolver *net.Resolver
+	if len(resolverList) > 0 {
+		transportResolver = dnsResolver.netResolver()
+		fmt.Printf("%s[*] Using %d custom DNS resolver(s): %s%s\n", ColorInfo, len(dnsResolver.servers), strings.Join(dnsResolver.servers, ", "), ColorReset)
+	}
 
 	// --- Shared HTTP Client Setup ---
 	// Note: CORS check uses its own client settings within checkCORS for specific needs
-	sharedClient := setupHTTPClient(*timeout, *workers) // From scanner.go
+	sharedClient := setupHTTPClient(*timeout, *workers, transportResolver) // From scanner.go
+	if scope != nil {
+		sharedClient.Transport = &scopedTransport{next: sharedClient.Transport, scope: scope}
+	}
+
+	// --- Match/Filter Options ---
+	filter, err := newResponseFilter(*matchString, *matchRegex, *matchCode, *filterCode, *filterSize, *filterWords, *filterTitle) // From filters.go
+	if err != nil {
+		fmt.Printf("%sError: %v%s\n", ColorError, err, ColorReset)
+		os.Exit(1)
+	}
+
+	// --- Data Extraction Rules ---
+	extractRules, err := parseExtractRules(extractValues) // From extract.go
+	if err != nil {
+		fmt.Printf("%sError: %v%s\n", ColorError, err, ColorReset)
+		os.Exit(1)
+	}
+
+	// --- YAML Templates ---
+	var templates []*checkTemplate
+	if *templatesSpec != "" {
+		templates, err = loadTemplates(*templatesSpec) // From templates.go
+		if err != nil {
+			fmt.Printf("%sError: %v%s\n", ColorError, err, ColorReset)
+			os.Exit(1)
+		}
+		fmt.Printf("%s[*] Loaded %d template(s) from %s%s\n", ColorInfo, len(templates), *templatesSpec, ColorReset)
+	}
+
+	// --- Raw Request Template ---
+	var rawTmpl *rawTemplate
+	if *rawFile != "" {
+		rawTmpl, err = loadRawTemplate(*rawFile, *timeout, transportResolver) // From rawrequest.go
+		if err != nil {
+			fmt.Printf("%sError: %v%s\n", ColorError, err, ColorReset)
+			os.Exit(1)
+		}
+		fmt.Printf("%s[*] Primary probe uses raw request template %s (%s)%s\n", ColorInfo, *rawFile, rawTmpl.method, ColorReset)
+		if *method != "" || len(headers) > 0 || *data != "" {
+			fmt.Printf("%s[!] -X/-H/-d do not apply to the raw template (-H and -cookie are still sent with the CORS check)%s\n", ColorWarning, ColorReset)
+		}
+	}
+
+	// --- Output Directory Setup (only once every option has been validated) ---
+	outputDir := *outputFlag
+	if outputDir == "" {
+		outputDir = defaultOutputDir(sources) // From inputs.go
+	}
+	outputDir, err = resolveOutputDir(outputDir, outputMode, startTime) // From output.go
+	if err != nil {
+		fmt.Printf("%sError: %v%s\n", ColorError, err, ColorReset)
+		os.Exit(1)
+	}
+	err = createOutputStructure(outputDir, outputMode == outputModeAppend) // From output.go
+	if err != nil {
+		fmt.Printf("%sError creating output structure in %s: %v%s\n", ColorError, outputDir, err, ColorReset)
+		os.Exit(1)
+	}
+	fmt.Printf("%s[*] Output will be saved to: %s%s%s\n", ColorInfo, ColorAccent, outputDir, ColorReset) // [source: 35]
+	if outputMode == outputModeAppend {
+		appendToFile(filepath.Join(outputDir, logFileName), fmt.Sprintf("--- Run started %s (hxscanner %s) ---", startTime.Format(time.RFC3339), version))
+	}
+
+	// --- Run Metadata (runmeta.go): written now, completed when main returns ---
+	runMeta := newRunMetadata(outputDir, outputMode, startTime, sources)
+	if err := runMeta.write(); err != nil {
+		fmt.Printf("%sWarning: failed to write %s: %v%s\n", ColorWarning, runMetadataFileName, err, ColorReset)
+	}
+
+	// --- CSV/Markdown Exports (export.go): headers now, rows as results arrive ---
+	var exporter *resultExporter
+	if exportCols != nil {
+		exporter, err = newResultExporter(*csvExport, *mdExport, exportCols)
+		if err != nil {
+			fmt.Printf("%sError: %v%s\n", ColorError, err, ColorReset)
+			os.Exit(1)
+		}
+	}
+
+	// --- Scope Enforcement (input now; follow-ups and requests via the queue and client) ---
+	if scope != nil {
+		scope.logPath = filepath.Join(outputDir, outOfScopeFileName)
+		initialTargets, err = scope.partition(initialTargets)
+		if err != nil {
+			fmt.Printf("%sError: %v%s\n", ColorError, err, ColorReset)
+			os.Exit(1)
+		}
+		defer initialTargets.close()
+		if dropped := totalTargets - initialTargets.len(); dropped > 0 {
+			fmt.Printf("%s[!] %d of %d targets are out of scope (see %s)%s\n", ColorWarning, dropped, totalTargets, outOfScopeFileName, ColorReset)
+		}
+		totalTargets = initialTargets.len()
+		if totalTargets == 0 {
+			fmt.Printf("%sWarning: No targets in %s are in scope.%s\n", ColorWarning, inputDescription, ColorReset)
+			os.Exit(0)
+		}
+	}
 
 	// --- Overall Statistics Setup ---
 	var successfulScans int64
 	var failedScans int64
 	statusCounts := make(map[int]int64)
 	var statusCountsMutex sync.Mutex // [source: 35]
+	defer func() {
+		if *htmlReport != "" {
+			err := writeHTMLReport(*htmlReport, startTime, runMeta.Inputs, outputDir, totalTargets, // From report.go
+				atomic.LoadInt64(&successfulScans), atomic.LoadInt64(&failedScans), statusCounts, &statusCountsMutex)
+			if err != nil {
+				fmt.Printf("%sError: %v%s\n", ColorError, err, ColorReset)
+			} else {
+				fmt.Printf("%s[*]%s HTML report saved to: %s%s%s\n", ColorInfo, ColorReset, ColorAccent, *htmlReport, ColorReset)
+			}
+		}
+		runMeta.finish(runResults{
+			Targets:    totalTargets,
+			Discovered: discoveredTotal(),
+			Successful: atomic.LoadInt64(&successfulScans),
+			Failed:     atomic.LoadInt64(&failedScans),
+		})
+	}()
+
+	opts := &scanOptions{
+		corsCheck:     *corsCheck,
+		faviconCheck:  *faviconCheck,
+		wafCheck:      *wafCheck || *wafProbe,
+		wafProbe:      *wafProbe,
+		methodsCheck:  *methodsCheck,
+		crlfCheck:     *crlfCheck,
+		cacheCheck:    *cacheCheck,
+		takeover:      *takeoverCheck,
+		resolver:      dnsResolver,
+		dnsRecords:    *dnsRecords || len(resolverList) > 0,
+		slowThreshold: *slowThreshold,
+		report:        *htmlReport != "",
+		export:        exporter,
+		request:       reqCfg,
+		raw:           rawTmpl,
+		templates:     templates,
+		filter:        filter,
+		extractRules:  extractRules,
+		jsCheck:       *jsCheck || *jsEnqueue,
+		jsEnqueue:     *jsEnqueue,
+		jsDepth:       *jsDepth,
+		crawl:         *crawl,
+		crawlDepth:    *crawlDepth,
+		crawlMax:      *crawlMax,
+		crawlRobots:   *robots,
+		harvest:       *harvest || *harvestEnqueue,
+		harvestQueue:  *harvestEnqueue,
+		scope:         scope,
+	}
 
 	// --- Run Initial Scan ---
-	// Pass corsCheck flag value here
 	runScanPhase(initialTargets, "Initial Scan", false, /* isRescan = false */
-		sharedClient, *workers, outputDir, *quiet, *corsCheck, // <-- Pass corsCheck value
+		sharedClient, *workers, outputDir, *quiet, opts,
 		&successfulScans, &failedScans,
 		statusCounts, &statusCountsMutex,
 	)
@@ -181,25 +513,34 @@ func main() {
 	printSummary("Initial Scan", startTime, totalTargets, &successfulScans, &failedScans, statusCounts, &statusCountsMutex, outputDir) // [source: 37]
 
 	// --- Prompt and Run Re-scan ---
+	// Only errors that might resolve themselves are worth retrying (see errorClass.retryable)
+	failedTargetsMutex.Lock()
+	targetsToRescan := make([]string, len(failedTargets))
+	copy(targetsToRescan, failedTargets)
+	failedTargetsMutex.Unlock() // [source: 38]
+	if !*retryAll {
+		var skipped map[errorClass]int
+		targetsToRescan, skipped = splitRetryable(targetsToRescan)
+		for class, n := range skipped {
+			fmt.Printf("%s[*] Not re-scanning %d target(s) with %s errors (use -retry-all to include them).%s\n", ColorInfo, n, errorClassLabels[class], ColorReset)
+		}
+	}
+
 	initialFailCount := atomic.LoadInt64(&failedScans)
-	if initialFailCount > 0 {
-		fmt.Printf("\n%s[*] %d targets failed initially. Do you want to re-scan them? (y/N): %s", ColorWarning, initialFailCount, ColorReset) // [source: 37]
+	if initialFailCount > 0 && len(targetsToRescan) > 0 {
+		fmt.Printf("\n%s[*] %d targets failed initially (%d retryable). Do you want to re-scan them? (y/N): %s", ColorWarning, initialFailCount, len(targetsToRescan), ColorReset) // [source: 37]
 		reader := bufio.NewReader(os.Stdin)
 		response, _ := reader.ReadString('\n')
 		response = strings.ToLower(strings.TrimSpace(response))
 
 		if response == "y" || response == "yes" { // [source: 38]
-			failedTargetsMutex.Lock()
-			targetsToRescan := make([]string, len(failedTargets))
-			copy(targetsToRescan, failedTargets)
-			// Optional: Clear global list if memory is a concern
-			// failedTargets = nil
-			failedTargetsMutex.Unlock() // [source: 38]
-
 			// Run the rescan phase
-			// Pass corsCheck flag value here too
-			runScanPhase(targetsToRescan, "Re-scan", true, /* isRescan = true */
-				sharedClient, *workers, outputDir, *quiet, *corsCheck, // <-- Pass corsCheck value
+			rescanList := newTargetList() // From normalize.go
+			for _, target := range targetsToRescan {
+				rescanList.add(target, inputSourceOf(target)) // From inputs.go
+			}
+			runScanPhase(rescanList, "Re-scan", true, /* isRescan = true */
+				sharedClient, *workers, outputDir, *quiet, opts,
 				&successfulScans, &failedScans,
 				statusCounts, &statusCountsMutex,
 			)
@@ -210,14 +551,19 @@ func main() {
 			fmt.Printf("%s[*] Skipping re-scan.%s\n", ColorInfo, ColorReset)
 			// Print the initial summary again as the final summary if no re-scan
 			fmt.Println("\n--- Final Summary (No Re-scan) ---")
-			fmt.Printf("Total Targets: %d\n", totalTargets)
+			fmt.Printf("Total Targets: %d%s\n", totalTargets, describeDiscovered())
 			fmt.Printf("%sSuccessful: %d%s\n", ColorSuccess, atomic.LoadInt64(&successfulScans), ColorReset)
 			fmt.Printf("%sFailed: %d%s\n", ColorError, atomic.LoadInt64(&failedScans), ColorReset)
 			// Re-print breakdown if needed, using same variables
-			printStatusBreakdown(statusCounts, &statusCountsMutex)                                                       // Extracted breakdown logic
+			printStatusBreakdown(statusCounts, &statusCountsMutex) // Extracted breakdown logic
+			// Same sections as printSummary (ui.go)
+			printSectionSummaries()
 			fmt.Printf("\n%s[*]%s Output saved to: %s%s%s\n", ColorInfo, ColorReset, ColorAccent, outputDir, ColorReset) // [source: 39]
 			fmt.Printf("%s[*]%s Scan complete.%s\n", ColorInfo, ColorReset, ColorReset)
 		}
+	} else if initialFailCount > 0 {
+		// Failures exist but none of them are worth retrying; the initial summary is final.
+		fmt.Printf("\n%s[*] No failed targets are retryable. Scan complete.%s\n", ColorInfo, ColorReset)
 	} else {
 		// If no initial failures, the initial summary is the final one.
 		fmt.Printf("\n%s[*] No targets failed initial scan. Scan complete.%s\n", ColorInfo, ColorReset) // [source: 40]
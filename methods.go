@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// methodsResult holds the risky HTTP methods found enabled on a target
+type methodsResult struct {
+	target     string
+	allow      []string // Methods advertised in the OPTIONS Allow header
+	corsAllow  []string // Methods advertised in Access-Control-Allow-Methods
+	enabled    []string // Risky methods confirmed by active tests, with a short note each
+	canaryPath string   // Path used for the PUT/DELETE tests
+	err        error
+}
+
+// riskyMethods are flagged when they show up in Allow/Access-Control-Allow-Methods
+var riskyMethods = map[string]bool{
+	"TRACE": true, "TRACK": true, "PUT": true, "DELETE": true, "CONNECT": true, "PATCH": true,
+	"PROPFIND": true, "PROPPATCH": true, "MKCOL": true, "COPY": true, "MOVE": true, "LOCK": true, "UNLOCK": true,
+}
+
+// methodOverrideHeaders are the headers frameworks commonly honour to tunnel a method through POST
+var methodOverrideHeaders = []string{"X-HTTP-Method-Override", "X-HTTP-Method", "X-Method-Override"}
+
+// checkMethods enumerates dangerous HTTP methods on a live target.
+// PUT/DELETE are only ever sent to a random canary path that cannot collide with real content,
+// and a successful PUT is cleaned up with a DELETE of the same path.
+func checkMethods(target string, mainResp *probeResponse, client *http.Client) methodsResult {
+	result := methodsResult{target: target}
+	base := mainResp.url
+	token := randomToken(8)
+	result.canaryPath = "/hxscanner-" + token + ".txt"
+	canaryURL := base.ResolveReference(&url.URL{Path: result.canaryPath}).String()
+
+	// --- 1. What does the server advertise? ---
+	resp, _, err := sendMethodRequest(client, "OPTIONS", base.String(), nil, nil)
+	if err != nil {
+		result.err = fmt.Errorf("OPTIONS request failed for %s: %w", base.String(), err)
+		return result
+	}
+	result.allow = splitMethodList(resp.Header.Get("Allow"))
+	result.corsAllow = splitMethodList(resp.Header.Get("Access-Control-Allow-Methods"))
+
+	// --- 2. TRACE: does it echo the request back (XST)? ---
+	traceHeader := map[string]string{"X-Hx-Trace": token}
+	if resp, body, err := sendMethodRequest(client, "TRACE", base.String(), nil, traceHeader); err == nil && resp.StatusCode == http.StatusOK {
+		if strings.Contains(string(body), token) {
+			result.enabled = append(result.enabled, "TRACE (request echoed, XST)")
+		} else {
+			result.enabled = append(result.enabled, "TRACE (200, no echo)")
+		}
+	}
+
+	// --- 3. PUT a canary file, confirm with GET, clean up with DELETE ---
+	putConfirmed := false
+	canaryBody := "hxscanner method check " + token
+	if resp, _, err := sendMethodRequest(client, "PUT", canaryURL, strings.NewReader(canaryBody), nil); err == nil && isSuccessStatus(resp.StatusCode) {
+		if getResp, body, err := sendMethodRequest(client, "GET", canaryURL, nil, nil); err == nil && getResp.StatusCode == http.StatusOK && string(body) == canaryBody {
+			putConfirmed = true
+			result.enabled = append(result.enabled, "PUT (canary file written and readable)")
+		} else {
+			result.enabled = append(result.enabled, fmt.Sprintf("PUT (accepted with %d, write not confirmed)", resp.StatusCode))
+		}
+	}
+
+	// --- 4. DELETE on the canary path (doubles as cleanup after a successful PUT) ---
+	if resp, _, err := sendMethodRequest(client, "DELETE", canaryURL, nil, nil); err == nil && isSuccessStatus(resp.StatusCode) {
+		if putConfirmed {
+			result.enabled = append(result.enabled, "DELETE (canary file removed)")
+		} else {
+			result.enabled = append(result.enabled, fmt.Sprintf("DELETE (accepted with %d)", resp.StatusCode))
+		}
+	}
+
+	// --- 5. Method override headers: tunnel TRACE through POST ---
+	// The trace token is only ever sent in the header (never in the URL), and only an echoed
+	// header line counts, so error pages that reflect the request path cannot match.
+	traceToken := randomToken(8)
+	echoedLine := "X-Hx-Trace: " + traceToken
+	for _, h := range methodOverrideHeaders {
+		headers := map[string]string{h: "TRACE", "X-Hx-Trace": traceToken}
+		if _, body, err := sendMethodRequest(client, "POST", canaryURL, nil, headers); err == nil && strings.Contains(string(body), echoedLine) {
+			result.enabled = append(result.enabled, h+" honoured (TRACE echoed via POST)")
+		}
+	}
+
+	return result
+}
+
+// sendMethodRequest sends a single request and returns the response with a bounded body.
+// The response body is already closed when this returns.
+func sendMethodRequest(client *http.Client, method, target string, body io.Reader, headers map[string]string) (*http.Response, []byte, error) {
+	req, err := http.NewRequest(method, target, body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create %s request for %s: %w", method, target, err)
+	}
+	req.Header.Set("User-Agent", "HyperScanner/1.4+MethodCheck")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	return resp, respBody, nil
+}
+
+// advertisedRisky returns the risky methods found in Allow and Access-Control-Allow-Methods
+func (r *methodsResult) advertisedRisky() []string {
+	seen := map[string]bool{}
+	var risky []string
+	for _, m := range append(append([]string{}, r.allow...), r.corsAllow...) {
+		if riskyMethods[m] && !seen[m] {
+			seen[m] = true
+			risky = append(risky, m)
+		}
+	}
+	return risky
+}
+
+// describeMethods formats a methods result for logs and terminal output
+func describeMethods(r *methodsResult) string {
+	parts := []string{}
+	if len(r.enabled) > 0 {
+		parts = append(parts, "enabled: "+strings.Join(r.enabled, ", "))
+	}
+	if adv := r.advertisedRisky(); len(adv) > 0 {
+		parts = append(parts, "advertised: "+strings.Join(adv, ","))
+	}
+	return strings.Join(parts, " | ")
+}
+
+// splitMethodList parses a comma separated method list such as "GET, POST, OPTIONS"
+func splitMethodList(v string) []string {
+	var methods []string
+	for _, m := range strings.Split(v, ",") {
+		m = strings.ToUpper(strings.TrimSpace(m))
+		if m != "" {
+			methods = append(methods, m)
+		}
+	}
+	return methods
+}
+
+// isSuccessStatus reports whether a status code is in the 2xx range
+func isSuccessStatus(code int) bool {
+	return code >= 200 && code < 300
+}
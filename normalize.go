@@ -0,0 +1,407 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net/netip"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// Input de-duplication modes for -dedup
+const (
+	dedupMemory = "memory" // A set in memory (default)
+	dedupDisk   = "disk"   // A bloom filter in memory, confirmed against bucket files on disk, for huge inputs
+	dedupOff    = "off"    // Keep every line, duplicates included
+)
+
+// idnaProfile converts internationalized hostnames to punycode. Unlike idna.Lookup it
+// allows underscores, which are common in real hostnames even if not strictly valid.
+var idnaProfile = idna.New(idna.MapForLookup(), idna.Transitional(false), idna.StrictDomainName(false))
+
+// normalizeTarget rewrites a target into its canonical URL form: scheme added (as in
+// scanTarget), scheme and host lowercased, trailing dot dropped, IDNA hostnames as
+// punycode, IPv6 addresses compressed and bracketed, default ports stripped, and
+// the path cleaned ("/" is dropped, "a//b/../c/" becomes "a/c/"; fragments go too).
+// "example.com", "http://EXAMPLE.com:80/" and "http://example.com" all normalize to
+// "http://example.com".
+func normalizeTarget(target string) (string, error) {
+	t, err := ParseTarget(target) // From target.go; handles bare IPv6, host:port and fragments
+	if err != nil {
+		return "", err
+	}
+	switch t.Kind {
+	case HostIPv6:
+		addr, _ := netip.ParseAddr(t.Host) // Validated by ParseTarget
+		t.Host = addr.String()             // Compressed, lowercase
+	case HostDomain:
+		if !isASCII(t.Host) {
+			if t.Host, err = idnaProfile.ToASCII(t.Host); err != nil {
+				return "", fmt.Errorf("%w '%s': %w", errInvalidTarget, target, err)
+			}
+			if !validLabels(t.Host) { // Mapping can empty a label ("0.\u00ad." -> "0..")
+				return "", fmt.Errorf("%w '%s': empty label after IDNA mapping (%s)", errInvalidTarget, target, t.Host)
+			}
+		}
+		t.Host = strings.ToLower(strings.TrimSuffix(t.Host, ".")) // After IDNA, which can drop characters (e.g. soft hyphens)
+		if t.Host == "" {
+			return "", fmt.Errorf("%w '%s': no host", errInvalidTarget, target)
+		}
+	}
+	if port, err := strconv.Atoi(t.Port); err == nil {
+		t.Port = strconv.Itoa(port) // "0080" -> "80"
+	}
+	if t.Port == defaultPorts[t.Scheme] {
+		t.Port = ""
+	}
+	t.Path = cleanTargetPath(t.Path) // On the escaped form, so "%2F" is never treated as a separator
+	if t.Path == "" && t.Query != "" {
+		t.Path = "/"
+	}
+	normalized := t.String()
+	if _, err := ParseTarget(normalized); err != nil { // IDNA maps e.g. fullwidth "＼" to ""
+		return "", fmt.Errorf("%w (after normalization to %s)", err, normalized)
+	}
+	return normalized, nil
+}
+
+// cleanTargetPath resolves "." and ".." and duplicate slashes, keeping a trailing slash
+// (which servers often treat differently). The root path becomes "".
+func cleanTargetPath(p string) string {
+	if p == "" || p == "/" {
+		return ""
+	}
+	cleaned := path.Clean("/" + p)
+	if strings.HasSuffix(p, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+	if cleaned == "/" {
+		return ""
+	}
+	return cleaned
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// dedupStats describes what the input preparation stage did
+type dedupStats struct {
+	inputs    int // Lines read
+	rewritten int // Lines whose normalized form differs from what was written
+	invalid   int // Lines that couldn't be normalized (kept as-is for the scanner to report)
+	collapsed int // Duplicates removed
+}
+
+// inputDedupStats is kept for the summary
+var inputDedupStats dedupStats
+
+// targetPreparer normalizes (unless disabled) and de-duplicates input targets as they
+// are read, keeping the first occurrence of each. Targets that fail to normalize are
+// kept unchanged so they surface as invalid_target errors instead of vanishing.
+// Every input target is claimed in the shared target set (queue.go) on the way, so
+// that set doubles as the de-duplication set and discoveries of an input target
+// aren't queued again.
+type targetPreparer struct {
+	normalize bool
+	dedup     bool
+	lines     dedupSet    // Exact lines, only needed with -normalize=false (claims compare normalized forms)
+	targets   *targetList // The targets to scan, in input order
+	stats     dedupStats
+}
+
+// newTargetPreparer validates the -dedup mode and sets up the sets it needs. With
+// -dedup disk the shared target set and the target list move to temporary files;
+// expected sizes the bloom filters.
+func newTargetPreparer(normalize bool, mode string, expected int) (*targetPreparer, error) {
+	p := &targetPreparer{normalize: normalize, dedup: mode != dedupOff}
+	switch mode {
+	case dedupMemory, dedupOff:
+		p.targets = newTargetList()
+		if p.dedup && !normalize {
+			p.lines = newMemoryDedup()
+		}
+		return p, nil
+	case dedupDisk:
+	default:
+		return nil, fmt.Errorf("invalid -dedup mode %q (use %s, %s or %s)", mode, dedupMemory, dedupDisk, dedupOff)
+	}
+
+	var err error
+	if p.targets, err = newTargetSpool(); err != nil {
+		return nil, err
+	}
+	seenTargets = newDiskDedup(expected) // From queue.go
+	if !normalize {
+		p.lines = newDiskDedup(expected)
+	}
+	return p, nil
+}
+
+// add prepares one input target read from the source with the given label
+func (p *targetPreparer) add(target, label string) error {
+	p.stats.inputs++
+	if p.normalize {
+		if n, err := normalizeTarget(target); err != nil {
+			p.stats.invalid++
+		} else if n != target {
+			p.stats.rewritten++
+			target = n
+		}
+	}
+	isNew, err := claimTargetKey(targetKey(target)) // From queue.go
+	if err == nil && p.lines != nil {
+		isNew, err = p.lines.add(target)
+	}
+	if err != nil {
+		return fmt.Errorf("de-duplication failed: %w", err)
+	}
+	if p.dedup && !isNew {
+		p.stats.collapsed++
+		return nil
+	}
+	return p.targets.add(target, label)
+}
+
+// close releases the exact-line set; the target list and the shared set live on
+func (p *targetPreparer) close() {
+	if p.lines != nil {
+		p.lines.close()
+	}
+}
+
+// dedupSet remembers keys; add reports whether a key is new
+type dedupSet interface {
+	add(key string) (bool, error)
+	close() error
+}
+
+// memoryDedup is a plain set
+type memoryDedup struct {
+	seen map[string]struct{}
+}
+
+func newMemoryDedup() *memoryDedup {
+	return &memoryDedup{seen: make(map[string]struct{})}
+}
+
+func (m *memoryDedup) add(key string) (bool, error) {
+	if _, ok := m.seen[key]; ok {
+		return false, nil
+	}
+	m.seen[key] = struct{}{}
+	return true, nil
+}
+
+func (m *memoryDedup) close() error { return nil }
+
+// --- On-disk de-duplication ---
+
+const (
+	diskDedupBuckets   = 256 // Bucket files keys are spread over by hash
+	bloomBitsPerKey    = 10  // ~1% false positives with bloomHashFunctions = 7
+	bloomHashFunctions = 7
+)
+
+// diskDedup keeps only a bloom filter in memory. A key the filter has never seen is
+// new; a possible hit is confirmed by scanning the key's bucket file, so the result is
+// exact while memory stays at ~10 bits per key.
+type diskDedup struct {
+	bloom   []uint64
+	bits    uint64
+	buckets [diskDedupBuckets]*bufio.Writer
+	files   [diskDedupBuckets]*os.File
+	sizes   [diskDedupBuckets]int64
+}
+
+func newDiskDedup(expected int) *diskDedup {
+	bits := uint64(expected*bloomBitsPerKey) + 64
+	return &diskDedup{bloom: make([]uint64, bits/64+1), bits: bits}
+}
+
+func (d *diskDedup) add(key string) (bool, error) {
+	h1, h2 := dedupHashes(key)
+	maybeSeen := true
+	for i := uint64(0); i < bloomHashFunctions; i++ {
+		bit := (h1 + i*h2) % d.bits
+		if d.bloom[bit/64]&(1<<(bit%64)) == 0 {
+			maybeSeen = false
+			d.bloom[bit/64] |= 1 << (bit % 64)
+		}
+	}
+	bucket := h1 % diskDedupBuckets
+	if maybeSeen {
+		found, err := d.bucketContains(bucket, key)
+		if err != nil || found {
+			return false, err
+		}
+	}
+	return true, d.appendToBucket(bucket, key)
+}
+
+// appendToBucket records a key in its bucket file, creating the file on first use
+func (d *diskDedup) appendToBucket(bucket uint64, key string) error {
+	if d.buckets[bucket] == nil {
+		f, err := createSpoolFile("hxscanner-dedup-*.txt")
+		if err != nil {
+			return err
+		}
+		d.files[bucket], d.buckets[bucket] = f, bufio.NewWriter(f)
+	}
+	n, err := d.buckets[bucket].WriteString(key + "\n")
+	d.sizes[bucket] += int64(n)
+	return err
+}
+
+// bucketContains scans a bucket file for a key (targets never contain newlines)
+func (d *diskDedup) bucketContains(bucket uint64, key string) (bool, error) {
+	if d.buckets[bucket] == nil {
+		return false, nil
+	}
+	if err := d.buckets[bucket].Flush(); err != nil {
+		return false, err
+	}
+	scanner := bufio.NewScanner(io.NewSectionReader(d.files[bucket], 0, d.sizes[bucket]))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if scanner.Text() == key {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
+
+func (d *diskDedup) close() error {
+	for _, f := range d.files {
+		if f != nil {
+			closeSpoolFile(f)
+		}
+	}
+	return nil
+}
+
+// dedupHashes derives the two hashes used for double hashing (h1 + i*h2)
+func dedupHashes(key string) (uint64, uint64) {
+	h := fnv.New128a()
+	h.Write([]byte(key))
+	sum := h.Sum(nil)
+	return binary.BigEndian.Uint64(sum[:8]), binary.BigEndian.Uint64(sum[8:]) | 1
+}
+
+// --- Input target lists ---
+
+// targetList holds the input targets of a scan phase, each with the label of the
+// source it came from: in memory, or spooled to a temporary file with -dedup disk
+type targetList struct {
+	jobs  []scanJob // In memory (source is set when the phase starts)
+	file  *os.File  // Or spooled: one "<label>\t<target>" line per target
+	spool *bufio.Writer
+	count int
+}
+
+func newTargetList() *targetList {
+	return &targetList{}
+}
+
+func newTargetSpool() (*targetList, error) {
+	f, err := createSpoolFile("hxscanner-targets-*.txt")
+	if err != nil {
+		return nil, err
+	}
+	return &targetList{file: f, spool: bufio.NewWriter(f)}, nil
+}
+
+// emptyLike returns a new, empty list stored the same way as l
+func (l *targetList) emptyLike() (*targetList, error) {
+	if l.file == nil {
+		return newTargetList(), nil
+	}
+	return newTargetSpool()
+}
+
+func (l *targetList) add(target, label string) error {
+	l.count++
+	if l.file == nil {
+		l.jobs = append(l.jobs, scanJob{target: target, input: label})
+		return nil
+	}
+	_, err := l.spool.WriteString(label + "\t" + target + "\n") // Labels are [A-Za-z0-9._-] (see expandInputs)
+	return err
+}
+
+func (l *targetList) len() int {
+	return l.count
+}
+
+// each calls fn for every target in order, reading a spooled list back from disk
+func (l *targetList) each(fn func(target, label string)) error {
+	if l.file == nil {
+		for _, job := range l.jobs {
+			fn(job.target, job.input)
+		}
+		return nil
+	}
+	if err := l.spool.Flush(); err != nil {
+		return err
+	}
+	if _, err := l.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	scanner := bufio.NewScanner(l.file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		label, target, _ := strings.Cut(scanner.Text(), "\t")
+		fn(target, label)
+	}
+	return scanner.Err()
+}
+
+func (l *targetList) close() {
+	if l.file != nil {
+		closeSpoolFile(l.file)
+	}
+	l.jobs = nil
+}
+
+// createSpoolFile creates a temporary file and unlinks it right away where the OS
+// allows it, so it never outlives the process however the run ends
+func createSpoolFile(pattern string) (*os.File, error) {
+	f, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	os.Remove(f.Name()) // Fails on Windows while open; closeSpoolFile retries
+	return f, nil
+}
+
+func closeSpoolFile(f *os.File) {
+	f.Close()
+	os.Remove(f.Name())
+}
+
+// printDedupSummary shows how much normalization and de-duplication shrank the input
+func printDedupSummary() {
+	s := inputDedupStats
+	if s.rewritten == 0 && s.collapsed == 0 {
+		return
+	}
+	fmt.Println("\nInput Normalization:")
+	fmt.Printf("  %-25s : %d\n", "Lines read", s.inputs)
+	fmt.Printf("  %-25s : %d\n", "Rewritten", s.rewritten)
+	fmt.Printf("  %-25s : %d\n", "Duplicates collapsed", s.collapsed)
+	if s.invalid > 0 {
+		fmt.Printf("  %-25s : %d\n", "Not normalizable", s.invalid)
+	}
+}
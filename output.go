@@ -5,17 +5,83 @@ import (
 	"os" // Ensure os package is imported
 	"path/filepath"
 	"sync"
+	"time"
 )
 
 // Constants for output file names
 const (
-	logFileName            = "log.txt"
-	existFileName          = "ip_exist.txt"
-	invalidFileName        = "ip_invalid.txt"
-	corsVulnerableFileName = "cors_vulnerable.txt"
-	unknownStatusFileName  = "unknown_status.txt"
+	logFileName              = "log.txt"
+	existFileName            = "ip_exist.txt"
+	invalidFileName          = "ip_invalid.txt"
+	corsVulnerableFileName   = "cors_vulnerable.txt"
+	unknownStatusFileName    = "unknown_status.txt"
+	faviconHashesFileName    = "favicon_hashes.txt"
+	wafDetectedFileName      = "waf_cdn.txt"
+	wafBlockedFileName       = "waf_blocked.txt"
+	methodsRiskyFileName     = "methods_risky.txt"
+	crlfInjectionFileName    = "crlf_injection.txt"
+	cachePoisonFileName      = "cache_poisoning.txt"
+	takeoverFileName         = "takeover.txt"
+	dnsRecordsFileName       = "dns_records.txt"
+	errorsDirName            = "errors"    // Holds one <error_class>.txt per failure class
+	extractedDirName         = "extracted" // Holds one <rule>.txt per -extract rule
+	bySourceDirName          = "by_source" // Holds one <input source>.txt per -i source
+	slowHostsFileName        = "slow_hosts.txt"
+	templateFindingsFileName = "template_findings.txt"
+	jsEndpointsFileName      = "js_endpoints.txt"
+	jsSecretsFileName        = "js_secrets.txt"
+	crawledFileName          = "crawled.txt"
+	harvestFileName          = "harvest.jsonl"
+	outOfScopeFileName       = "out_of_scope.txt"
+	runMetadataFileName      = "run.json" // Command line, version, times and options of the run
 )
 
+// Output directory modes for when the directory already has content
+const (
+	outputModeNew         = ""            // Refuse to reuse a non-empty directory (default)
+	outputModeOverwrite   = "overwrite"   // Delete the previous results first
+	outputModeAppend      = "append"      // Keep previous results and add to them
+	outputModeTimestamped = "timestamped" // Use a new <dir>_<timestamp> directory every run
+)
+
+// outputFiles are the files createOutputStructure pre-creates in the output directory
+var outputFiles = []string{
+	existFileName,
+	invalidFileName,
+	logFileName,
+	corsVulnerableFileName,
+	unknownStatusFileName,
+	faviconHashesFileName,
+	wafDetectedFileName,
+	wafBlockedFileName,
+	methodsRiskyFileName,
+	crlfInjectionFileName,
+	cachePoisonFileName,
+	takeoverFileName,
+	dnsRecordsFileName,
+	slowHostsFileName,
+	templateFindingsFileName,
+	jsEndpointsFileName,
+	jsSecretsFileName,
+	crawledFileName,
+	harvestFileName,
+	outOfScopeFileName,
+}
+
+// outputDirs lists the directories createOutputStructure creates in the output directory
+func outputDirs() []string {
+	dirs := make([]string, 0, len(statusCategories)+4)
+	for _, categoryName := range statusCategories {
+		dirs = append(dirs, categoryName)
+	}
+	return append(dirs,
+		"unknown_category", // For status codes outside 1xx-5xx
+		errorsDirName,      // Failures split by error class
+		extractedDirName,   // -extract values split by rule
+		bySourceDirName,    // Results split by input source
+	)
+}
+
 // Mutex to protect file writing operations across goroutines
 var fileWriteMutex sync.Mutex
 
@@ -38,37 +104,93 @@ func appendToFile(path, line string) {
 	}
 }
 
-// createOutputStructure prepares the output directory and ensures all required files exist
-func createOutputStructure(base string) error {
+// resolveOutputDir picks the directory a run writes to and clears it for -overwrite.
+// A non-empty directory is only reused with -overwrite or -append, and -overwrite
+// only clears directories that have a run.json, removing just what hxscanner wrote.
+func resolveOutputDir(dir, mode string, start time.Time) (string, error) {
+	if mode == outputModeTimestamped {
+		base := dir + "_" + start.Format("20060102_150405")
+		dir = base
+		for n := 2; ; n++ {
+			if _, err := os.Stat(dir); os.IsNotExist(err) {
+				return dir, nil
+			}
+			dir = fmt.Sprintf("%s-%d", base, n)
+		}
+	}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return dir, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("cannot use output directory %s: %w", dir, err)
+	}
+	if len(entries) == 0 {
+		return dir, nil
+	}
+	switch mode {
+	case outputModeAppend:
+		return dir, nil
+	case outputModeOverwrite:
+		if !isOutputDir(dir) {
+			return "", fmt.Errorf("refusing to overwrite %s: it has no %s, so it doesn't look like hxscanner output", dir, runMetadataFileName)
+		}
+		if err := clearOutputDir(dir); err != nil {
+			return "", fmt.Errorf("failed to clear output directory %s: %w", dir, err)
+		}
+		return dir, nil
+	default:
+		return "", fmt.Errorf("output directory %s is not empty; use -overwrite, -append or -timestamped (or another -o)", dir)
+	}
+}
+
+// isOutputDir reports whether a directory holds results of an earlier run
+func isOutputDir(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, runMetadataFileName))
+	return err == nil
+}
+
+// clearOutputDir deletes what an earlier run created in dir (its files and
+// directories, and run.json) and leaves everything else, and dir itself, alone
+func clearOutputDir(dir string) error {
+	for _, name := range append([]string{runMetadataFileName}, outputFiles...) {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	for _, name := range outputDirs() {
+		if err := os.RemoveAll(filepath.Join(dir, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createOutputStructure prepares the output directory and ensures all required files
+// exist. With appendMode, files from an earlier run are kept instead of truncated.
+func createOutputStructure(base string, appendMode bool) error {
 	err := os.MkdirAll(base, os.ModePerm)
 	if err != nil {
 		return fmt.Errorf("failed to create base output directory %s: %w", base, err)
 	}
 
-	// Pre-create category directories based on statusCategories map
-	for _, categoryName := range statusCategories {
-		dir := filepath.Join(base, categoryName)
-		err = os.MkdirAll(dir, os.ModePerm)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "%sWarning: failed to create category directory %s: %v%s\n", ColorWarning, dir, err, ColorReset)
+	// Pre-create the category directories and the per-class/rule/source directories
+	for _, name := range outputDirs() {
+		dir := filepath.Join(base, name)
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			fmt.Fprintf(os.Stderr, "%sWarning: failed to create directory %s: %v%s\n", ColorWarning, dir, err, ColorReset)
 		}
 	}
-	// Also create directory for unknown categories if needed
-	unknownCatDir := filepath.Join(base, "unknown_category")
-	os.MkdirAll(unknownCatDir, os.ModePerm)
 
-	// Pre-create auxiliary files using constants
-	extras := []string{
-		existFileName,
-		invalidFileName,
-		logFileName,
-		corsVulnerableFileName,
-		unknownStatusFileName,
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if appendMode {
+		flags = os.O_CREATE | os.O_WRONLY
 	}
-	for _, name := range extras {
+	// Pre-create auxiliary files
+	for _, name := range outputFiles {
 		filePath := filepath.Join(base, name)
-		// Create file if it doesn't exist, truncate if it does
-		f, err := os.Create(filePath)
+		// Create file if it doesn't exist, truncate it if it does (unless appending)
+		f, err := os.OpenFile(filePath, flags, 0644)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "%sWarning: failed to create/truncate auxiliary file %s: %v%s\n", ColorWarning, filePath, err, ColorReset)
 		}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/schollz/progressbar/v3"
+)
+
+// scanJob is one unit of work: an input target, or a URL discovered while scanning
+type scanJob struct {
+	target string
+	parent string // URL the job was discovered on ("" for input targets)
+	depth  int    // 0 for input targets, parent's depth + 1 for discoveries
+	source string // How the job was found: "input", or the discovering feature (e.g. "js")
+	input  string // Label of the input source the job (or the input target it descends from) came from
+}
+
+// jobQueue feeds the workers of one scan phase and lets them add follow-up jobs.
+// It counts outstanding jobs so the channel is closed only after every job has been
+// handled, including jobs discovered along the way.
+type jobQueue struct {
+	jobs    chan scanJob
+	pending sync.WaitGroup
+	bar     *progressbar.ProgressBar
+	scope   *scopeRules // Follow-up jobs outside -scope are logged instead of queued (nil = no scope)
+}
+
+// newJobQueue creates a queue fed with a phase's input targets, tagged with source.
+// A spooled list is read back from disk as the workers consume it. Call closeWhenDone
+// once the workers are running.
+func newJobQueue(initial *targetList, source string, bar *progressbar.ProgressBar, buffer int, scope *scopeRules) *jobQueue {
+	q := &jobQueue{jobs: make(chan scanJob, buffer), bar: bar, scope: scope}
+	total := initial.len()
+	q.pending.Add(total)
+	go func() {
+		sent := 0
+		err := initial.each(func(target, label string) {
+			q.jobs <- scanJob{target: target, source: source, input: label}
+			sent++
+		})
+		if err != nil {
+			// Don't leave the phase waiting for targets that will never come
+			fmt.Printf("%s[!] Only %d of %d targets could be read back from the target list: %v%s\n", ColorError, sent, total, err, ColorReset)
+			q.pending.Add(sent - total)
+		}
+	}()
+	return q
+}
+
+// closeWhenDone closes the job channel once every job (input and discovered) is done
+func (q *jobQueue) closeWhenDone() {
+	go func() {
+		q.pending.Wait()
+		close(q.jobs)
+	}()
+}
+
+// done marks a job as handled. Workers call it after sending the job's result, and
+// after enqueuing anything discovered from it, so the count never drops to zero early.
+func (q *jobQueue) done() {
+	q.pending.Done()
+}
+
+// enqueue adds a discovered job unless its target is out of scope or was already
+// scanned or queued in any phase. It never blocks: workers are the consumers, so a
+// worker waiting on a full channel could deadlock the pool.
+func (q *jobQueue) enqueue(job scanJob) bool {
+	if reason := q.scope.checkTarget(job.target); reason != "" {
+		q.scope.reject(job.target, "follow-up", fmt.Sprintf("%s (%s from %s)", reason, job.source, job.parent))
+		return false
+	}
+	if !claimTarget(job.target) {
+		return false
+	}
+	q.pending.Add(1)
+	q.bar.AddMax(1)
+	recordDiscovered(job.source)
+	go func() { q.jobs <- job }()
+	return true
+}
+
+// --- Target de-duplication and discovery statistics (shared by all phases) ---
+var seenTargets dedupSet = newMemoryDedup()   // On disk with -dedup disk (normalize.go)
+var discoveredCounts = make(map[string]int64) // Discovered targets per source
+var seenTargetsMutex sync.Mutex
+
+// claimTarget records a target and reports whether it was new. "host", "http://HOST:80"
+// and "http://host/" are the same page, so targets are compared in normalized form.
+// If the on-disk set fails, the target counts as new: scanning twice beats skipping it.
+func claimTarget(target string) bool {
+	isNew, err := claimTargetKey(targetKey(target))
+	return isNew || err != nil
+}
+
+// claimTargetKey records a key (in targetKey form) in the shared target set
+func claimTargetKey(key string) (bool, error) {
+	seenTargetsMutex.Lock()
+	defer seenTargetsMutex.Unlock()
+	return seenTargets.add(key)
+}
+
+// targetKey is the form targets are compared in: normalized, or as-is if that fails
+func targetKey(target string) string {
+	if n, err := normalizeTarget(target); err == nil { // From normalize.go
+		return n
+	}
+	return target
+}
+
+// recordDiscovered counts a discovered target under its source
+func recordDiscovered(source string) {
+	seenTargetsMutex.Lock()
+	discoveredCounts[source]++
+	seenTargetsMutex.Unlock()
+}
+
+// discoveredTotal returns how many targets were added during the scan
+func discoveredTotal() int {
+	seenTargetsMutex.Lock()
+	defer seenTargetsMutex.Unlock()
+	total := 0
+	for _, n := range discoveredCounts {
+		total += int(n)
+	}
+	return total
+}
+
+// describeDiscovered formats the discovered count for the "Total Targets" line ("" if none)
+func describeDiscovered() string {
+	if n := discoveredTotal(); n > 0 {
+		return fmt.Sprintf(" (+%d discovered)", n)
+	}
+	return ""
+}
+
+// printDiscoverySummary shows how many extra targets each discovery feature queued
+func printDiscoverySummary() {
+	seenTargetsMutex.Lock()
+	defer seenTargetsMutex.Unlock()
+	if len(discoveredCounts) == 0 {
+		return
+	}
+	sources := make([]string, 0, len(discoveredCounts))
+	for source := range discoveredCounts {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	fmt.Println("\nDiscovered Targets:")
+	for _, source := range sources {
+		fmt.Printf("  %s%-25s%s : %d\n", ColorAccent, source, ColorReset, discoveredCounts[source])
+	}
+}
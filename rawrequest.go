@@ -0,0 +1,298 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rawTemplate is a Burp-style raw HTTP request loaded with -raw. It is rendered per
+// target and written to the socket byte for byte, so header order, casing and even
+// malformed lines reach the server exactly as written.
+type rawTemplate struct {
+	path    string
+	raw     string // Template text with CRLF line endings
+	method  string // First word of the request line, used to decide whether a body follows
+	dialer  *net.Dialer
+	timeout time.Duration // Deadline for the whole exchange
+}
+
+// rawPlaceholder matches {{Name}} placeholders in a raw template
+var rawPlaceholder = regexp.MustCompile(`\{\{(\w+)\}\}`)
+
+// rawPlaceholderNames are the placeholders placeholderValues provides, plus
+// {{ContentLength}}, which render fills in once the body is known
+var rawPlaceholderNames = map[string]bool{
+	"Host": true, "Hostname": true, "Port": true, "Path": true,
+	"Scheme": true, "BaseURL": true, "RandStr": true, "RandInt": true,
+	"ContentLength": true,
+}
+
+// rawContentLength is replaced with the rendered body's length in the request head
+const rawContentLength = "{{ContentLength}}"
+
+// loadRawTemplate reads a raw request file. Files saved with bare \n line endings
+// (as most editors and Burp's "Copy to file" do) are converted to CRLF; files that
+// already contain CRLF are left untouched.
+func loadRawTemplate(path string, timeout time.Duration, resolver *net.Resolver) (*rawTemplate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read raw request file %s: %w", path, err)
+	}
+	raw := string(data)
+	if !strings.Contains(raw, "\r\n") {
+		raw = strings.ReplaceAll(raw, "\n", "\r\n")
+	}
+	// A request without a body still needs the blank line ending the headers
+	if !strings.Contains(raw, "\r\n\r\n") {
+		raw = strings.TrimRight(raw, "\r\n") + "\r\n\r\n"
+	}
+
+	requestLine, _, _ := strings.Cut(raw, "\r\n")
+	method, _, _ := strings.Cut(strings.TrimSpace(requestLine), " ")
+	if method == "" {
+		return nil, fmt.Errorf("raw request file %s has no request line", path)
+	}
+	for _, m := range rawPlaceholder.FindAllStringSubmatch(raw, -1) {
+		if !rawPlaceholderNames[m[1]] {
+			return nil, fmt.Errorf("raw request file %s uses unknown placeholder {{%s}}", path, m[1])
+		}
+	}
+
+	return &rawTemplate{
+		path:    path,
+		raw:     raw,
+		method:  strings.ToUpper(method),
+		dialer:  &net.Dialer{Timeout: timeout, Resolver: resolver},
+		timeout: timeout,
+	}, nil
+}
+
+// placeholderValues computes the {{Name}} values for a target URL. {{RandStr}} and
+// {{RandInt}} get one value per call, so repeated uses within a request agree.
+func placeholderValues(u *url.URL) map[string]string {
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	randInt, _ := rand.Int(rand.Reader, big.NewInt(1_000_000_000))
+	return map[string]string{
+		"Host":     u.Host,
+		"Hostname": u.Hostname(),
+		"Port":     port,
+		"Path":     path,
+		"Scheme":   u.Scheme,
+		"BaseURL":  u.Scheme + "://" + u.Host,
+		"RandStr":  randomToken(8), // From utils.go
+		"RandInt":  randInt.String(),
+	}
+}
+
+// renderPlaceholders substitutes known placeholders in s; unknown ones are left as-is
+func renderPlaceholders(s string, values map[string]string) string {
+	return rawPlaceholder.ReplaceAllStringFunc(s, func(m string) string {
+		if v, ok := values[m[2:len(m)-2]]; ok {
+			return v
+		}
+		return m
+	})
+}
+
+// render substitutes the placeholders for one target. A literal Content-Length header
+// is sent as written, even if it doesn't match the body (desync probes rely on that);
+// write "Content-Length: {{ContentLength}}" to have it computed instead.
+func (t *rawTemplate) render(u *url.URL) []byte {
+	rendered := renderPlaceholders(t.raw, placeholderValues(u))
+
+	head, body, _ := strings.Cut(rendered, "\r\n\r\n")
+	head = strings.ReplaceAll(head, rawContentLength, strconv.Itoa(len(body)))
+	return []byte(head + "\r\n\r\n" + body)
+}
+
+// sendRawRequest renders the template for a target, sends it over a plain or TLS
+// connection and parses the reply into the same probeResponse scanTarget returns
+func sendRawRequest(target string, t *rawTemplate) (*probeResponse, error) {
+	parsed, err := ParseTarget(target) // From target.go
+	if err != nil {
+		return nil, err
+	}
+	parsedURL := parsed.URL()
+	payload := t.render(parsedURL)
+	addr := parsed.DialAddress()
+
+	ctx, cancel := context.WithTimeout(context.Background(), t.timeout)
+	defer cancel()
+
+	timing := &probeTiming{}
+	start := time.Now()
+	conn, err := t.dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("raw request failed for %s: %w", parsedURL, err)
+	}
+	defer conn.Close()
+	timing.connect = time.Since(start) // Includes DNS; the dialer doesn't report it separately
+	remoteAddr := conn.RemoteAddr().String()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if parsedURL.Scheme == "https" {
+		tlsStart := time.Now()
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: parsedURL.Hostname(), NextProtos: []string{"http/1.1"}})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			return nil, fmt.Errorf("raw request failed for %s: %w", parsedURL, err)
+		}
+		timing.tls = time.Since(tlsStart)
+		conn = tlsConn
+	}
+
+	if _, err := conn.Write(payload); err != nil {
+		return nil, fmt.Errorf("raw request failed for %s: %w", parsedURL, err)
+	}
+
+	reader := bufio.NewReader(conn)
+	if _, err := reader.Peek(1); err != nil {
+		return nil, fmt.Errorf("raw request failed for %s: no response: %w", parsedURL, err)
+	}
+	timing.ttfb = time.Since(start)
+
+	statusCode, header, body, err := readRawResponse(reader, t.method)
+	if err != nil {
+		return nil, fmt.Errorf("raw request failed for %s: %w", parsedURL, err)
+	}
+	timing.total = time.Since(start)
+
+	return &probeResponse{
+		url:        parsedURL,
+		statusCode: statusCode,
+		header:     header,
+		body:       body,
+		remoteAddr: remoteAddr,
+		timing:     timing,
+	}, nil
+}
+
+// readRawResponse is a deliberately lenient HTTP/1.x response parser: a raw request may
+// well provoke a broken reply, and we'd rather report what came back than fail.
+// It skips interim 1xx responses, ignores header lines without a colon, and reads the
+// body by chunked encoding, Content-Length or until the connection closes (in that
+// order), capped at maxBodySize. A body truncated by the server is returned as-is.
+func readRawResponse(r *bufio.Reader, method string) (int, http.Header, []byte, error) {
+	for {
+		statusLine, err := readRawLine(r)
+		if err != nil {
+			return 0, nil, nil, fmt.Errorf("reading status line: %w", err)
+		}
+		if statusLine == "" {
+			continue // Tolerate stray blank lines before the status line
+		}
+		fields := strings.Fields(statusLine)
+		if len(fields) < 2 || !strings.HasPrefix(fields[0], "HTTP/") {
+			return 0, nil, nil, fmt.Errorf("malformed HTTP status line %q", statusLine)
+		}
+		statusCode, err := strconv.Atoi(fields[1])
+		if err != nil || statusCode < 100 || statusCode > 999 {
+			return 0, nil, nil, fmt.Errorf("malformed HTTP status code in %q", statusLine)
+		}
+
+		header := http.Header{}
+		for {
+			line, err := readRawLine(r)
+			if err != nil {
+				if len(header) > 0 && err == io.EOF {
+					break // Server closed right after the headers
+				}
+				return 0, nil, nil, fmt.Errorf("reading headers: %w", err)
+			}
+			if line == "" {
+				break
+			}
+			name, value, ok := strings.Cut(line, ":")
+			if !ok || strings.TrimSpace(name) == "" {
+				continue
+			}
+			header.Add(http.CanonicalHeaderKey(strings.TrimSpace(name)), strings.TrimSpace(value))
+		}
+
+		if statusCode >= 100 && statusCode < 200 && statusCode != 101 {
+			continue // Interim response; the real one follows
+		}
+		if method == "HEAD" || statusCode == 101 || statusCode == 204 || statusCode == 304 {
+			return statusCode, header, nil, nil
+		}
+
+		var body []byte
+		switch {
+		case strings.Contains(strings.ToLower(header.Get("Transfer-Encoding")), "chunked"):
+			body = readChunkedBody(r)
+		case header.Get("Content-Length") != "":
+			n, convErr := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+			if convErr != nil || n < 0 {
+				body, _ = io.ReadAll(io.LimitReader(r, maxBodySize))
+				break
+			}
+			body, _ = io.ReadAll(io.LimitReader(r, min(n, maxBodySize)))
+		default:
+			body, _ = io.ReadAll(io.LimitReader(r, maxBodySize))
+		}
+		return statusCode, header, body, nil
+	}
+}
+
+// readRawLine reads one line, accepting both CRLF and bare LF endings
+func readRawLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// readChunkedBody decodes a chunked body, stopping quietly at the first malformed
+// chunk or at maxBodySize and returning whatever was decoded so far
+func readChunkedBody(r *bufio.Reader) []byte {
+	var body bytes.Buffer
+	for body.Len() < maxBodySize {
+		sizeLine, err := readRawLine(r)
+		if err != nil {
+			break
+		}
+		sizeHex, _, _ := strings.Cut(sizeLine, ";") // Drop chunk extensions
+		size, err := strconv.ParseInt(strings.TrimSpace(sizeHex), 16, 64)
+		if err != nil || size < 0 {
+			break
+		}
+		if size == 0 {
+			break // Trailers, if any, are not needed
+		}
+		n, _ := io.CopyN(&body, r, min(size, int64(maxBodySize-body.Len())))
+		if n < size {
+			break
+		}
+		readRawLine(r) // CRLF after the chunk data
+	}
+	return body.Bytes()
+}
@@ -0,0 +1,609 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- Technology fingerprinting (shown in the report) ---
+
+// techCookies maps session cookie names to the framework that sets them
+var techCookies = []struct{ prefix, tech string }{
+	{"PHPSESSID", "PHP"},
+	{"JSESSIONID", "Java"},
+	{"ASP.NET_SessionId", "ASP.NET"},
+	{"ASPSESSIONID", "ASP"},
+	{"laravel_session", "Laravel"},
+	{"ci_session", "CodeIgniter"},
+	{"connect.sid", "Express"},
+	{"django_language", "Django"},
+	{"wordpress_", "WordPress"},
+	{"_shopify", "Shopify"},
+}
+
+// techBodyMarkers are strings that give a framework away in HTML
+var techBodyMarkers = []struct{ marker, tech string }{
+	{"/wp-content/", "WordPress"},
+	{"/wp-includes/", "WordPress"},
+	{"__NEXT_DATA__", "Next.js"},
+	{"__NUXT__", "Nuxt.js"},
+	{"ng-version=", "Angular"},
+	{"data-reactroot", "React"},
+	{"/sites/default/files/", "Drupal"},
+	{"Joomla!", "Joomla"},
+}
+
+// generatorPattern finds <meta name="generator" content="...">
+var generatorPattern = regexp.MustCompile(`(?i)<meta[^>]+name=["']generator["'][^>]+content=["']([^"']+)["']`)
+
+// detectTech lists server software and frameworks visible in a response: Server,
+// X-Powered-By and similar headers, the generator meta tag, session cookie names
+// and a few body markers. Values are kept as sent (e.g. "nginx/1.25.3"); a bare
+// name is left out when a versioned entry already names it ("PHP" after "PHP/8.2").
+func detectTech(header http.Header, body []byte) []string {
+	var tech []string
+	add := func(t string) {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			return
+		}
+		for _, existing := range tech {
+			if strings.HasPrefix(strings.ToLower(existing), strings.ToLower(t)) {
+				return
+			}
+		}
+		tech = append(tech, t)
+	}
+	add(header.Get("Server"))
+	for _, v := range header.Values("X-Powered-By") {
+		add(v)
+	}
+	if v := header.Get("X-AspNet-Version"); v != "" {
+		add("ASP.NET " + v)
+	}
+	if v := header.Get("X-AspNetMvc-Version"); v != "" {
+		add("ASP.NET MVC " + v)
+	}
+	add(header.Get("X-Generator"))
+	if m := generatorPattern.FindSubmatch(body); m != nil {
+		add(string(m[1]))
+	}
+	for _, c := range header.Values("Set-Cookie") {
+		for _, tc := range techCookies {
+			if strings.HasPrefix(c, tc.prefix) {
+				add(tc.tech)
+			}
+		}
+	}
+	for _, bm := range techBodyMarkers {
+		if strings.Contains(string(body), bm.marker) {
+			add(bm.tech)
+		}
+	}
+	return tech
+}
+
+// --- Result collection for -html ---
+
+// reportFinding is one issue shown with a severity badge
+type reportFinding struct {
+	Severity string // critical, high, medium, low or info (as in templates)
+	Check    string
+	Target   string
+	Detail   string
+}
+
+// reportRow is one target in the results table
+type reportRow struct {
+	Target    string
+	Status    int
+	Class     string // "2xx".."5xx", "error" or "filtered"; used by the class filter
+	Outcome   string // Status code with its description, or the error class
+	Err       string
+	Title     string
+	Tech      string
+	TotalMs   int64 // -1 when there is no timing
+	TTFBMs    int64
+	Timing    string // Full per-phase breakdown
+	Source    string
+	Parent    string
+	Input     string
+	Rescanned bool
+	Findings  []reportFinding
+	SevRank   int // Rank of the most severe finding (len(templateSeverities) if none)
+}
+
+// Rows are collected across phases; a re-scan result replaces the failed attempt
+var reportRows []reportRow
+var reportIndex = make(map[string]int)
+var reportMutex sync.Mutex
+
+// recordReportRow adds (or, for re-scans, replaces) a result's row
+func recordReportRow(res scanResult) {
+	row := reportRow{
+		Target:    res.target,
+		Status:    res.statusCode,
+		Title:     res.title,
+		Tech:      strings.Join(res.tech, ", "),
+		TotalMs:   -1,
+		TTFBMs:    -1,
+		Source:    res.source,
+		Parent:    res.parent,
+		Input:     res.input,
+		Rescanned: res.isRescan,
+		Findings:  resultFindings(res),
+		SevRank:   len(templateSeverities),
+	}
+	switch {
+	case res.err != nil:
+		row.Class, row.Outcome, row.Err = "error", errorClassLabels[res.errClass], res.err.Error()
+	case res.filtered != "":
+		row.Class, row.Outcome = "filtered", fmt.Sprintf("%d (filtered: %s)", res.statusCode, res.filtered)
+	default:
+		row.Class, row.Outcome = fmt.Sprintf("%dxx", res.statusCode/100), fmt.Sprintf("%d %s", res.statusCode, statusCodes[res.statusCode])
+	}
+	if res.timing != nil {
+		row.TotalMs, row.TTFBMs, row.Timing = res.timing.total.Milliseconds(), res.timing.ttfb.Milliseconds(), res.timing.describe()
+	}
+	for _, f := range row.Findings {
+		if r := severityRank(f.Severity); r >= 0 && r < row.SevRank {
+			row.SevRank = r
+		}
+	}
+
+	reportMutex.Lock()
+	defer reportMutex.Unlock()
+	if i, ok := reportIndex[res.target]; ok {
+		reportRows[i] = row
+		return
+	}
+	reportIndex[res.target] = len(reportRows)
+	reportRows = append(reportRows, row)
+}
+
+// resultFindings turns a result's check outcomes into severity-rated findings
+func resultFindings(res scanResult) []reportFinding {
+	var findings []reportFinding
+	add := func(severity, check, detail string) {
+		findings = append(findings, reportFinding{Severity: severity, Check: check, Target: res.target, Detail: detail})
+	}
+	if res.cors != nil && res.cors.vulnerable {
+		add(corsSeverity(res.cors.details), "CORS", res.cors.details)
+	}
+	if t := res.takeover; t != nil && t.err == nil {
+		if t.vulnerable {
+			add("high", "Subdomain takeover", fmt.Sprintf("%s (chain: %s; %s)", t.service, strings.Join(t.cnameChain, " -> "), t.evidence))
+		} else if t.service != "" {
+			add("medium", "Takeover candidate", t.evidence)
+		}
+	}
+	if res.crlf != nil {
+		for _, f := range res.crlf.findings {
+			add("high", "CRLF injection", fmt.Sprintf("%s payload %s: %s (%s)", f.point, f.payload, f.url, f.evidence))
+		}
+	}
+	if res.cache != nil {
+		for _, f := range res.cache.findings {
+			if f.persisted {
+				add("high", "Cache poisoning", fmt.Sprintf("%s: %s reflected and cached (%s)", f.header, f.value, f.url))
+			} else {
+				add("low", "Unkeyed header", fmt.Sprintf("%s: %s reflected but not cached (%s)", f.header, f.value, f.url))
+			}
+		}
+	}
+	if m := res.methods; m != nil && m.err == nil {
+		if len(m.enabled) > 0 {
+			add("medium", "Risky methods", describeMethods(m))
+		} else if len(m.advertisedRisky()) > 0 {
+			add("low", "Risky methods", describeMethods(m))
+		}
+	}
+	if res.js != nil {
+		for _, s := range res.js.secrets {
+			add("high", "JS secret", fmt.Sprintf("%s in %s", s.rule, s.script))
+		}
+	}
+	if res.templates != nil {
+		for _, f := range res.templates.findings {
+			add(f.severity, "Template "+f.templateID, describeFinding(f))
+		}
+	}
+	if w := res.waf; w != nil && (w.waf != "" || w.cdn != "" || w.blocked) {
+		add("info", "WAF/CDN", describeWAF(w))
+	}
+	for _, rule := range sortedKeys(res.extracted) {
+		add("info", "Extract "+rule, strings.Join(res.extracted[rule], ", "))
+	}
+	return findings
+}
+
+// corsSeverity rates a CORS result from its details: a reflected origin with
+// credentials is critical, a reflected origin high, wildcard or null origins medium
+func corsSeverity(details string) string {
+	switch {
+	case strings.Contains(details, "(CRITICAL)"):
+		return "critical"
+	case strings.HasPrefix(details, "Reflects Origin"):
+		return "high"
+	default:
+		return "medium"
+	}
+}
+
+// sortedKeys returns a map's keys in order
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// --- Report rendering ---
+
+// reportBar is one bar of the status code chart
+type reportBar struct {
+	Label         string
+	Count         int64
+	X, Y, W, H    int
+	LabelX, TextY int
+	Color         string
+}
+
+// reportCount is a labelled number in the summary
+type reportCount struct {
+	Label string
+	Count int64
+}
+
+// reportData is everything the template renders
+type reportData struct {
+	Version     string
+	Generated   string
+	Started     string
+	Duration    string
+	Inputs      []string
+	OutputDir   string
+	Targets     int
+	Discovered  int
+	Successful  int64
+	Failed      int64
+	Filtered    int
+	Bars        []reportBar
+	ChartWidth  int
+	ChartHeight int
+	Statuses    []reportCount
+	Errors      []reportCount
+	Severities  []reportCount
+	Notes       []reportCount
+	Timings     []timingStat
+	Findings    []reportFinding
+	Rows        []reportRow
+}
+
+// statusColor colors chart bars by status class
+func statusColor(label string) string {
+	switch label[0] {
+	case '1':
+		return "#8a8f98"
+	case '2':
+		return "#2e9e5b"
+	case '3':
+		return "#3b7dd8"
+	case '4':
+		return "#e0892b"
+	case '5':
+		return "#d64545"
+	}
+	return "#6b4fbb" // Failed requests
+}
+
+// writeHTMLReport renders the collected results and summary into one self-contained file
+func writeHTMLReport(path string, start time.Time, inputs []string, outputDir string, targets int, successful, failed int64, statusCounts map[int]int64, statusCountsMutex *sync.Mutex) error {
+	data := reportData{
+		Version:    version,
+		Generated:  time.Now().Format("2006-01-02 15:04:05 MST"),
+		Started:    start.Format("2006-01-02 15:04:05 MST"),
+		Duration:   time.Since(start).Round(time.Second).String(),
+		Inputs:     inputs,
+		OutputDir:  outputDir,
+		Targets:    targets,
+		Discovered: discoveredTotal(),
+		Successful: successful,
+		Failed:     failed,
+		Timings:    timingStats(),
+	}
+
+	// Status codes, then failures as a last bar
+	statusCountsMutex.Lock()
+	codes := make([]int, 0, len(statusCounts))
+	for code, n := range statusCounts {
+		if n > 0 {
+			codes = append(codes, code)
+		}
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		data.Statuses = append(data.Statuses, reportCount{fmt.Sprintf("%d %s", code, statusCodes[code]), statusCounts[code]})
+	}
+	statusCountsMutex.Unlock()
+	bars := make([]reportCount, 0, len(data.Statuses)+1)
+	for i, code := range codes {
+		bars = append(bars, reportCount{fmt.Sprint(code), data.Statuses[i].Count})
+	}
+	if failed > 0 {
+		bars = append(bars, reportCount{"failed", failed})
+	}
+	data.Bars, data.ChartWidth, data.ChartHeight = layoutBars(bars)
+
+	errorClassMutex.Lock()
+	for class, n := range errorClassCounts {
+		if n > 0 {
+			data.Errors = append(data.Errors, reportCount{errorClassLabels[class], n})
+		}
+	}
+	errorClassMutex.Unlock()
+	sort.Slice(data.Errors, func(i, j int) bool { return data.Errors[i].Count > data.Errors[j].Count })
+
+	reportMutex.Lock()
+	data.Rows = append([]reportRow(nil), reportRows...)
+	reportMutex.Unlock()
+	severityCounts := make(map[string]int64)
+	for _, row := range data.Rows {
+		if row.Class == "filtered" {
+			data.Filtered++
+		}
+		for _, f := range row.Findings {
+			data.Findings = append(data.Findings, f)
+			severityCounts[f.Severity]++
+		}
+	}
+	for _, s := range templateSeverities {
+		if severityCounts[s] > 0 {
+			data.Severities = append(data.Severities, reportCount{s, severityCounts[s]})
+		}
+	}
+	sort.SliceStable(data.Findings, func(i, j int) bool {
+		return severityRank(data.Findings[i].Severity) < severityRank(data.Findings[j].Severity)
+	})
+
+	if s := inputDedupStats; s.collapsed > 0 {
+		data.Notes = append(data.Notes, reportCount{"Duplicate input lines collapsed", int64(s.collapsed)})
+	}
+	outOfScopeMutex.Lock()
+	for _, stage := range []string{"input", "follow-up", "request"} {
+		if n := outOfScopeCounts[stage]; n > 0 {
+			data.Notes = append(data.Notes, reportCount{"Out of scope (" + stage + ")", int64(n)})
+		}
+	}
+	outOfScopeMutex.Unlock()
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create report %s: %w", path, err)
+	}
+	defer f.Close()
+	if err := reportTemplate.Execute(f, data); err != nil {
+		return fmt.Errorf("failed to render report %s: %w", path, err)
+	}
+	return nil
+}
+
+// layoutBars positions chart bars, scaled to the largest count
+func layoutBars(counts []reportCount) ([]reportBar, int, int) {
+	const barWidth, gap, plotHeight, top, left = 36, 14, 160, 20, 10
+	var max int64
+	for _, c := range counts {
+		if c.Count > max {
+			max = c.Count
+		}
+	}
+	bars := make([]reportBar, len(counts))
+	for i, c := range counts {
+		h := int(c.Count * plotHeight / max)
+		if h < 2 {
+			h = 2
+		}
+		x := left + i*(barWidth+gap)
+		bars[i] = reportBar{
+			Label: c.Label, Count: c.Count,
+			X: x, Y: top + plotHeight - h, W: barWidth, H: h,
+			LabelX: x + barWidth/2, TextY: top + plotHeight - h - 5,
+			Color: statusColor(c.Label),
+		}
+	}
+	return bars, left*2 + len(counts)*(barWidth+gap), top + plotHeight + 24
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"add": func(a, b int) int { return a + b },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>HyperScanner report - {{.Started}}</title>
+<style>
+:root { --bg: #f6f7f9; --card: #fff; --text: #1d2330; --muted: #667085; --line: #e3e6eb; --accent: #3b5bdb; }
+* { box-sizing: border-box; }
+body { margin: 0; font: 14px/1.45 -apple-system, "Segoe UI", Roboto, Helvetica, Arial, sans-serif; background: var(--bg); color: var(--text); }
+header { background: #1d2330; color: #fff; padding: 20px 32px; }
+header h1 { margin: 0 0 4px; font-size: 22px; }
+header p { margin: 0; color: #b8c0cc; }
+main { padding: 24px 32px; max-width: 1600px; margin: 0 auto; }
+section { background: var(--card); border: 1px solid var(--line); border-radius: 8px; padding: 18px 22px; margin-bottom: 22px; }
+h2 { font-size: 17px; margin: 0 0 14px; }
+.cards { display: flex; flex-wrap: wrap; gap: 14px; }
+.card { flex: 1 1 140px; border: 1px solid var(--line); border-radius: 6px; padding: 12px 14px; }
+.card .n { font-size: 24px; font-weight: 600; }
+.card .l { color: var(--muted); font-size: 12px; text-transform: uppercase; letter-spacing: .04em; }
+.grid { display: grid; grid-template-columns: repeat(auto-fit, minmax(300px, 1fr)); gap: 22px; }
+table { border-collapse: collapse; width: 100%; }
+th, td { text-align: left; padding: 6px 8px; border-bottom: 1px solid var(--line); vertical-align: top; }
+th { font-size: 12px; color: var(--muted); text-transform: uppercase; letter-spacing: .03em; white-space: nowrap; }
+td.num, th.num { text-align: right; }
+#results th[data-key] { cursor: pointer; user-select: none; }
+#results th[data-key]:hover { color: var(--accent); }
+#results th.asc::after { content: " \25B2"; }
+#results th.desc::after { content: " \25BC"; }
+#results td { word-break: break-word; }
+.badge { display: inline-block; padding: 1px 7px; border-radius: 10px; font-size: 11px; font-weight: 600; color: #fff; text-transform: uppercase; margin: 1px 2px 1px 0; white-space: nowrap; }
+.sev-critical { background: #8b1a1a; } .sev-high { background: #d64545; } .sev-medium { background: #e0892b; }
+.sev-low { background: #c9a227; } .sev-info { background: #3b7dd8; }
+.st { font-weight: 600; white-space: nowrap; }
+.c-2xx { color: #2e9e5b; } .c-3xx { color: #3b7dd8; } .c-4xx { color: #e0892b; } .c-5xx { color: #d64545; } .c-error { color: #6b4fbb; } .c-filtered { color: var(--muted); }
+.muted { color: var(--muted); }
+.small { font-size: 12px; }
+.controls { display: flex; flex-wrap: wrap; gap: 10px; margin-bottom: 12px; align-items: center; }
+.controls input, .controls select { padding: 6px 8px; border: 1px solid var(--line); border-radius: 5px; font: inherit; }
+.controls input { flex: 1 1 260px; }
+svg text { font-size: 11px; fill: var(--text); }
+a { color: var(--accent); text-decoration: none; }
+a:hover { text-decoration: underline; }
+</style>
+</head>
+<body>
+<header>
+<h1>HyperScanner report</h1>
+<p>Started {{.Started}} &middot; took {{.Duration}} &middot; generated {{.Generated}} by hxscanner {{.Version}}</p>
+</header>
+<main>
+
+<section>
+<h2>Summary</h2>
+<div class="cards">
+<div class="card"><div class="n">{{.Targets}}</div><div class="l">Input targets</div></div>
+<div class="card"><div class="n">{{.Discovered}}</div><div class="l">Discovered</div></div>
+<div class="card"><div class="n c-2xx">{{.Successful}}</div><div class="l">Successful</div></div>
+<div class="card"><div class="n c-5xx">{{.Failed}}</div><div class="l">Failed</div></div>
+<div class="card"><div class="n">{{.Filtered}}</div><div class="l">Filtered</div></div>
+<div class="card"><div class="n">{{len .Findings}}</div><div class="l">Findings</div></div>
+</div>
+<p class="muted small">Inputs: {{range $i, $in := .Inputs}}{{if $i}}, {{end}}{{$in}}{{end}} &middot; Output directory: {{.OutputDir}}</p>
+</section>
+
+<div class="grid">
+{{if .Bars}}<section>
+<h2>Status code distribution</h2>
+<svg width="{{.ChartWidth}}" height="{{.ChartHeight}}" viewBox="0 0 {{.ChartWidth}} {{.ChartHeight}}" role="img" aria-label="Status code distribution">
+<line x1="0" y1="{{add .ChartHeight -24}}" x2="{{.ChartWidth}}" y2="{{add .ChartHeight -24}}" stroke="#c8ccd4"/>
+{{range .Bars}}<rect x="{{.X}}" y="{{.Y}}" width="{{.W}}" height="{{.H}}" rx="3" fill="{{.Color}}"><title>{{.Label}}: {{.Count}}</title></rect>
+<text x="{{.LabelX}}" y="{{.TextY}}" text-anchor="middle">{{.Count}}</text>
+<text x="{{.LabelX}}" y="{{add $.ChartHeight -8}}" text-anchor="middle">{{.Label}}</text>
+{{end}}</svg>
+<table class="small">{{range .Statuses}}<tr><td>{{.Label}}</td><td class="num">{{.Count}}</td></tr>{{end}}</table>
+</section>{{end}}
+
+{{if .Errors}}<section>
+<h2>Error breakdown</h2>
+<table>{{range .Errors}}<tr><td>{{.Label}}</td><td class="num">{{.Count}}</td></tr>{{end}}</table>
+</section>{{end}}
+
+{{if or .Severities .Notes}}<section>
+<h2>Findings by severity</h2>
+{{if .Severities}}<p>{{range .Severities}}<span class="badge sev-{{.Label}}">{{.Label}}</span> {{.Count}} &nbsp; {{end}}</p>{{else}}<p class="muted">No findings.</p>{{end}}
+{{if .Notes}}<table class="small">{{range .Notes}}<tr><td>{{.Label}}</td><td class="num">{{.Count}}</td></tr>{{end}}</table>{{end}}
+</section>{{end}}
+
+{{if .Timings}}<section>
+<h2>Latency percentiles</h2>
+<table><tr><th>Phase</th><th class="num">p50</th><th class="num">p90</th><th class="num">p99</th><th class="num">Samples</th></tr>
+{{range .Timings}}<tr><td>{{.Phase}}</td><td class="num">{{.P50}}</td><td class="num">{{.P90}}</td><td class="num">{{.P99}}</td><td class="num">{{.Samples}}</td></tr>{{end}}
+</table>
+</section>{{end}}
+</div>
+
+{{if .Findings}}<section>
+<h2>Findings</h2>
+<table>
+<tr><th>Severity</th><th>Check</th><th>Target</th><th>Detail</th></tr>
+{{range .Findings}}<tr><td><span class="badge sev-{{.Severity}}">{{.Severity}}</span></td><td>{{.Check}}</td><td>{{.Target}}</td><td class="small">{{.Detail}}</td></tr>
+{{end}}</table>
+</section>{{end}}
+
+<section>
+<h2>Results</h2>
+<div class="controls">
+<input id="q" type="search" placeholder="Filter by target, title, tech, finding...">
+<select id="cls">
+<option value="">All results</option>
+<option value="2xx">2xx</option><option value="3xx">3xx</option><option value="4xx">4xx</option><option value="5xx">5xx</option>
+<option value="error">Failed</option><option value="filtered">Filtered</option>
+</select>
+<select id="sev">
+<option value="99">Any findings or none</option>
+<option value="0">Critical</option><option value="1">High or worse</option><option value="2">Medium or worse</option>
+<option value="3">Low or worse</option><option value="4">Any finding</option>
+</select>
+<span id="count" class="muted small"></span>
+</div>
+<table id="results">
+<thead><tr>
+<th data-key="0">Target</th><th data-key="1" data-num>Status</th><th data-key="2">Title</th><th data-key="3">Tech</th>
+<th data-key="4" data-num>Findings</th><th data-key="5" data-num class="num">Total ms</th><th data-key="6" data-num class="num">TTFB ms</th><th data-key="7">Source</th>
+</tr></thead>
+<tbody>
+{{range .Rows}}<tr data-cls="{{.Class}}" data-sev="{{.SevRank}}">
+<td data-v="{{.Target}}"><a href="{{.Target}}" rel="noreferrer">{{.Target}}</a>{{if .Rescanned}} <span class="muted small">(re-scan)</span>{{end}}</td>
+<td data-v="{{if eq .Class "error"}}1000{{else}}{{.Status}}{{end}}"><span class="st c-{{.Class}}"{{if .Err}} title="{{.Err}}"{{end}}>{{.Outcome}}</span></td>
+<td data-v="{{.Title}}">{{.Title}}</td>
+<td data-v="{{.Tech}}" class="small">{{.Tech}}</td>
+<td data-v="{{.SevRank}}">{{range .Findings}}<span class="badge sev-{{.Severity}}" title="{{.Check}}: {{.Detail}}">{{.Check}}</span>{{end}}</td>
+<td data-v="{{.TotalMs}}" class="num"{{if .Timing}} title="{{.Timing}}"{{end}}>{{if ge .TotalMs 0}}{{.TotalMs}}{{end}}</td>
+<td data-v="{{.TTFBMs}}" class="num">{{if ge .TTFBMs 0}}{{.TTFBMs}}{{end}}</td>
+<td data-v="{{.Source}}" class="small">{{.Source}}{{if .Input}} <span class="muted">({{.Input}})</span>{{end}}{{if .Parent}}<br><span class="muted">via {{.Parent}}</span>{{end}}</td>
+</tr>
+{{end}}</tbody>
+</table>
+</section>
+
+</main>
+<script>
+(function () {
+  var table = document.getElementById("results");
+  var body = table.tBodies[0];
+  var rows = Array.prototype.slice.call(body.rows);
+  var q = document.getElementById("q"), cls = document.getElementById("cls"), sev = document.getElementById("sev");
+  var count = document.getElementById("count");
+
+  function apply() {
+    var text = q.value.toLowerCase(), c = cls.value, s = parseInt(sev.value, 10), shown = 0;
+    rows.forEach(function (r) {
+      var ok = (!c || r.dataset.cls === c) && (s === 99 || parseInt(r.dataset.sev, 10) <= s) &&
+        (!text || r.textContent.toLowerCase().indexOf(text) >= 0 || r.innerHTML.toLowerCase().indexOf(text) >= 0);
+      r.style.display = ok ? "" : "none";
+      if (ok) shown++;
+    });
+    count.textContent = shown + " of " + rows.length + " results";
+  }
+  [q, cls, sev].forEach(function (el) { el.addEventListener("input", apply); });
+
+  Array.prototype.forEach.call(table.tHead.rows[0].cells, function (th) {
+    if (!th.dataset.key) return;
+    th.addEventListener("click", function () {
+      var key = parseInt(th.dataset.key, 10), num = th.hasAttribute("data-num");
+      var dir = th.classList.contains("asc") ? -1 : 1;
+      Array.prototype.forEach.call(th.parentNode.cells, function (o) { o.classList.remove("asc", "desc"); });
+      th.classList.add(dir === 1 ? "asc" : "desc");
+      rows.sort(function (a, b) {
+        var x = a.cells[key].dataset.v, y = b.cells[key].dataset.v;
+        if (num) return dir * (parseFloat(x) - parseFloat(y));
+        return dir * x.localeCompare(y);
+      });
+      rows.forEach(function (r) { body.appendChild(r); });
+    });
+  });
+  apply();
+})();
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// defaultUserAgent is sent by the primary probe unless -ua overrides it
+const defaultUserAgent = "HyperScanner/1.4"
+
+// randomUserAgentMode is the -ua value that picks a user agent per request from the built-in list
+const randomUserAgentMode = "random"
+
+// builtinUserAgents are common browser user agents used by -ua random
+var builtinUserAgents = []string{
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+	"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+	"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:125.0) Gecko/20100101 Firefox/125.0",
+	"Mozilla/5.0 (X11; Linux x86_64; rv:125.0) Gecko/20100101 Firefox/125.0",
+	"Mozilla/5.0 (Macintosh; Intel Mac OS X 14_4_1) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4.1 Safari/605.1.15",
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36 Edg/124.0.2478.80",
+	"Mozilla/5.0 (iPhone; CPU iPhone OS 17_4_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4.1 Mobile/15E148 Safari/604.1",
+	"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Mobile Safari/537.36",
+}
+
+// headerFlag collects repeated -H 'Name: value' flags, keeping their order
+type headerFlag [][2]string
+
+func (h *headerFlag) String() string {
+	parts := make([]string, len(*h))
+	for i, kv := range *h {
+		parts[i] = kv[0] + ": " + kv[1]
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (h *headerFlag) Set(v string) error {
+	name, value, ok := strings.Cut(v, ":")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" {
+		return fmt.Errorf("header must be in 'Name: value' form, got %q", v)
+	}
+	*h = append(*h, [2]string{name, strings.TrimSpace(value)})
+	return nil
+}
+
+// requestConfig is the user's customisation of outgoing requests (-X, -H, -d, -cookie, -ua)
+type requestConfig struct {
+	method    string
+	headers   [][2]string
+	body      []byte // nil means no body
+	cookie    string
+	userAgent string // "" keeps each check's default, randomUserAgentMode picks per request
+}
+
+// newRequestConfig validates the request flags. -d @file reads the body from a file,
+// and like curl, a body without an explicit -X switches the method to POST.
+func newRequestConfig(method string, headers headerFlag, data, cookie, userAgent string) (*requestConfig, error) {
+	cfg := &requestConfig{
+		method:    strings.ToUpper(strings.TrimSpace(method)),
+		headers:   headers,
+		cookie:    cookie,
+		userAgent: userAgent,
+	}
+	if data != "" {
+		if strings.HasPrefix(data, "@") {
+			b, err := os.ReadFile(data[1:])
+			if err != nil {
+				return nil, fmt.Errorf("failed to read request body from %s: %w", data[1:], err)
+			}
+			cfg.body = b
+		} else {
+			cfg.body = []byte(data)
+		}
+	}
+	if cfg.method == "" {
+		cfg.method = "GET"
+		if cfg.body != nil {
+			cfg.method = "POST"
+		}
+	}
+	return cfg, nil
+}
+
+// bodyReader returns a fresh reader over the configured body (nil if there is none)
+func (c *requestConfig) bodyReader() io.Reader {
+	if c == nil || c.body == nil {
+		return nil
+	}
+	return bytes.NewReader(c.body)
+}
+
+// apply sets the user agent, custom headers and cookie on a request.
+// defaultUA is used when no -ua was given; checks call apply before setting
+// any headers of their own so check-specific headers always win.
+func (c *requestConfig) apply(req *http.Request, defaultUA string) {
+	if c == nil {
+		req.Header.Set("User-Agent", defaultUA)
+		return
+	}
+	switch c.userAgent {
+	case "":
+		req.Header.Set("User-Agent", defaultUA)
+	case randomUserAgentMode:
+		req.Header.Set("User-Agent", builtinUserAgents[rand.IntN(len(builtinUserAgents))])
+	default:
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+	// The first -H for a name replaces any default (e.g. User-Agent); repeats are added
+	seen := map[string]bool{}
+	for _, kv := range c.headers {
+		if strings.EqualFold(kv[0], "Host") {
+			req.Host = kv[1] // Go ignores a Host entry in req.Header
+			continue
+		}
+		key := http.CanonicalHeaderKey(kv[0])
+		if seen[key] {
+			req.Header.Add(key, kv[1])
+		} else {
+			req.Header.Set(key, kv[1])
+			seen[key] = true
+		}
+	}
+	if c.cookie != "" {
+		req.Header.Set("Cookie", c.cookie)
+	}
+}
@@ -4,20 +4,23 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/schollz/progressbar/v3"
 )
 
 // processResults handles incoming results from workers for both initial and re-scan phases.
-// Added corsCheck bool parameter to know if CORS details should be expected/printed.
+// opts tells it which optional check results should be expected/printed.
 func processResults(
 	results <-chan scanResult,
 	resultWg *sync.WaitGroup,
 	bar *progressbar.ProgressBar,
 	quiet bool,
-	corsCheck bool, // <-- Added parameter
+	opts *scanOptions,
 	outputDir string,
 	successfulScans *int64,
 	failedScans *int64,
@@ -31,6 +34,20 @@ func processResults(
 	existPath := filepath.Join(outputDir, existFileName)
 	invalidPath := filepath.Join(outputDir, invalidFileName)
 	corsVulnPath := filepath.Join(outputDir, corsVulnerableFileName) // <-- Added CORS vuln file path
+	faviconPath := filepath.Join(outputDir, faviconHashesFileName)
+	wafPath := filepath.Join(outputDir, wafDetectedFileName)
+	wafBlockedPath := filepath.Join(outputDir, wafBlockedFileName)
+	methodsPath := filepath.Join(outputDir, methodsRiskyFileName)
+	crlfPath := filepath.Join(outputDir, crlfInjectionFileName)
+	cachePath := filepath.Join(outputDir, cachePoisonFileName)
+	takeoverPath := filepath.Join(outputDir, takeoverFileName)
+	templateFindingsPath := filepath.Join(outputDir, templateFindingsFileName)
+	jsEndpointsPath := filepath.Join(outputDir, jsEndpointsFileName)
+	jsSecretsPath := filepath.Join(outputDir, jsSecretsFileName)
+	crawledPath := filepath.Join(outputDir, crawledFileName)
+	harvestPath := filepath.Join(outputDir, harvestFileName)
+	dnsPath := filepath.Join(outputDir, dnsRecordsFileName)
+	slowPath := filepath.Join(outputDir, slowHostsFileName)
 
 	for res := range results {
 		// Safely increment progress bar for each processed result
@@ -44,11 +61,65 @@ func processResults(
 			desc = "" // No description needed if there's an error
 		}
 
-		// Display primary result (status code or error) unless in quiet mode
-		colorPrint(res.target, res.statusCode, desc, res.err, quiet, res.isRescan) // Call ui function [source: 43]
+		// --- Rows for the -html report (report.go), filtered results included ---
+		if opts.report {
+			recordReportRow(res)
+		}
+		opts.export.write(res) // -csv/-md rows as they arrive (export.go; no-op without them)
+
+		// --- Per input source (by_source/<label>.txt), filtered results included ---
+		if res.input != "" {
+			outcome := fmt.Sprintf("%d", res.statusCode)
+			if res.err != nil {
+				outcome = "ERROR[" + res.errClass.String() + "]"
+			} else if res.filtered != "" {
+				outcome += " FILTERED"
+			} else if res.waf != nil && res.waf.blocked {
+				outcome += " WAF_BLOCKED"
+			}
+			if res.isRescan {
+				outcome = "RESCAN " + outcome
+			}
+			appendToFile(filepath.Join(outputDir, bySourceDirName, res.input+".txt"), res.target+" "+outcome)
+			recordInputResult(res.input, res.err == nil && (res.waf == nil || !res.waf.blocked), res.isRescan) // From inputs.go
+		}
+
+		// Live results removed by -match-*/-filter-* are logged and counted but kept out of
+		// the terminal and the status files. Their check findings are still reported below;
+		// only the detail lines that would hang off the (unprinted) result line are dropped.
+		filtered := res.err == nil && res.filtered != ""
+		// A WAF block page isn't the application's real answer: it goes to waf_blocked.txt
+		// instead of the status files (e.g. 4xx/403.txt) and doesn't count as live
+		blocked := res.err == nil && !filtered && res.waf != nil && res.waf.blocked
+		resQuiet := quiet || filtered
+		if (filtered || blocked) && res.isRescan {
+			// It did come back, so it no longer counts as a failure
+			atomic.AddInt64(failedScans, -1)
+			clearErrorClass(res.target)
+		}
+		if blocked {
+			appendToFile(wafBlockedPath, fmt.Sprintf("%s -> %d (%s)", res.target, res.statusCode, res.waf.waf))
+			appendToFile(logPath, fmt.Sprintf("[-] WAF BLOCKED %s -> %d (%s)", res.target, res.statusCode, res.waf.waf)+describeInputSource(res.input))
+		}
+		if filtered {
+			recordFiltered(res.filtered)
+			appendToFile(logPath, fmt.Sprintf("[-] FILTERED %s -> %d (%s)", res.target, res.statusCode, res.filtered))
+		} else {
+			// Display primary result (status code or error) unless in quiet mode
+			colorPrint(res.target, res.statusCode, desc, res.err, quiet, res.isRescan) // Call ui function [source: 43]
+		}
+
+		// --- DNS Records ---
+		if res.dns != nil {
+			line := fmt.Sprintf("%s %s %s", res.target, res.dns.host, describeDNS(res.dns))
+			if res.remoteAddr != "" {
+				line += " connected=" + res.remoteAddr
+			}
+			appendToFile(dnsPath, line)
+		}
 
 		// --- Handle CORS Result Processing (if check was enabled) ---
-		if corsCheck && res.cors != nil { // Check if CORS check was performed (res.cors is not nil)
+		if opts.corsCheck && res.cors != nil { // Check if CORS check was performed (res.cors is not nil)
 			if res.cors.err != nil {
 				// Log CORS check error separately
 				logMsg := fmt.Sprintf("[!] CORS Check Error for %s: %v", res.cors.target, res.cors.err)
@@ -72,7 +143,7 @@ func processResults(
 				// appendToFile(logPath, fmt.Sprintf("[✓] CORS OK: %s (%s)", res.cors.target, res.cors.details))
 
 				// Print non-vulnerable status only if NOT in quiet mode
-				if !quiet {
+				if !resQuiet {
 					// Indicate CORS check was OK inline or below main result line
 					// Using a less prominent color like Info or just default reset
 					// fmt.Printf("%s      ↳ CORS OK: %s%s\n", ColorInfo, res.cors.details, ColorReset)
@@ -81,9 +152,196 @@ func processResults(
 		}
 		// --- End CORS Result Handling ---
 
+		// --- WAF/CDN Detection Recording ---
+		if opts.wafCheck && res.waf != nil {
+			if res.waf.err != nil {
+				appendToFile(logPath, fmt.Sprintf("[!] WAF Probe Error for %s: %v", res.waf.target, res.waf.err))
+			}
+			recordWAF(res.waf)
+			if res.waf.throttled > 0 {
+				appendToFile(logPath, fmt.Sprintf("[!] WAF THROTTLING %s -> %d, backing off %s", res.waf.target, res.statusCode, res.waf.throttled.Round(time.Second)))
+			}
+			if res.waf.waf != "" || res.waf.cdn != "" || res.waf.throttled > 0 {
+				appendToFile(wafPath, fmt.Sprintf("%s -> %s", res.waf.target, describeWAF(res.waf)))
+				if !resQuiet {
+					fmt.Printf("%s      ↳ %s%s\n", ColorAccent, describeWAF(res.waf), ColorReset)
+				}
+			}
+		}
+
+		// --- Risky HTTP Methods ---
+		if opts.methodsCheck && res.methods != nil {
+			if res.methods.err != nil {
+				appendToFile(logPath, fmt.Sprintf("[!] Methods Check Error for %s: %v", res.methods.target, res.methods.err))
+			} else if desc := describeMethods(res.methods); desc != "" {
+				appendToFile(methodsPath, fmt.Sprintf("%s -> %s", res.methods.target, desc))
+				appendToFile(logPath, fmt.Sprintf("[!] RISKY METHODS: %s -> %s", res.methods.target, desc))
+				// Printed even in quiet mode as it's a finding
+				fmt.Printf("%s[METHODS]%s %s -> %s%s%s\n", ColorFinding, ColorReset, res.methods.target, ColorWarning, desc, ColorReset)
+			}
+		}
+
+		// --- CRLF Injection Findings ---
+		if opts.crlfCheck && res.crlf != nil {
+			if res.crlf.err != nil {
+				appendToFile(logPath, fmt.Sprintf("[!] CRLF Check Error for %s: %v", res.crlf.target, res.crlf.err))
+			}
+			for _, f := range res.crlf.findings {
+				msg := fmt.Sprintf("%s [%s] payload=%s url=%s (%s)", res.crlf.target, f.point, f.payload, f.url, f.evidence)
+				appendToFile(crlfPath, msg)
+				appendToFile(logPath, "[!] CRLF INJECTION: "+msg)
+				fmt.Printf("%s[CRLF]%s %s -> %s%s via %s%s\n", ColorFinding, ColorReset, res.crlf.target, ColorWarning, f.point, f.payload, ColorReset)
+			}
+		}
+
+		// --- Cache Poisoning Findings ---
+		if opts.cacheCheck && res.cache != nil {
+			if res.cache.err != nil {
+				appendToFile(logPath, fmt.Sprintf("[!] Cache Check Error for %s: %v", res.cache.target, res.cache.err))
+			}
+			for _, f := range res.cache.findings {
+				kind := "REFLECTED (not cached)"
+				if f.persisted {
+					kind = "POISONED"
+				}
+				msg := fmt.Sprintf("%s %s header=%s value=%s buster=%s url=%s (%s)", res.cache.target, kind, f.header, f.value, f.cacheBuster, f.url, f.evidence)
+				appendToFile(cachePath, msg)
+				appendToFile(logPath, "[!] CACHE "+msg)
+				if f.persisted {
+					fmt.Printf("%s[CACHE POISON]%s %s -> %s%s (buster %s)%s\n", ColorFinding, ColorReset, res.cache.target, ColorWarning, f.header, f.cacheBuster, ColorReset)
+				} else if !resQuiet {
+					fmt.Printf("%s      ↳ unkeyed %s reflected but not cached (buster %s)%s\n", ColorAccent, f.header, f.cacheBuster, ColorReset)
+				}
+			}
+		}
+
+		// --- Subdomain Takeover ---
+		if opts.takeover && res.takeover != nil {
+			if res.takeover.err != nil {
+				appendToFile(logPath, fmt.Sprintf("[!] Takeover Check Error for %s: %v", res.takeover.target, res.takeover.err))
+			} else if res.takeover.vulnerable {
+				msg := fmt.Sprintf("[%s] %s -> %s (chain: %s; %s)", res.takeover.severity, res.takeover.target,
+					res.takeover.service, strings.Join(res.takeover.cnameChain, " -> "), res.takeover.evidence)
+				appendToFile(takeoverPath, msg)
+				appendToFile(logPath, "[!] TAKEOVER "+msg)
+				// Always printed: these are the most valuable findings
+				fmt.Printf("%s[TAKEOVER %s]%s %s -> %s%s (%s)%s\n", ColorFinding, res.takeover.severity, ColorReset,
+					res.takeover.target, ColorWarning, res.takeover.service, res.takeover.evidence, ColorReset)
+			} else if res.takeover.service != "" {
+				appendToFile(logPath, fmt.Sprintf("[?] TAKEOVER CANDIDATE %s -> %s", res.takeover.target, res.takeover.evidence))
+			}
+		}
+
+		// --- Extracted Data ---
+		// Each value is written once per rule across the whole scan, with the first target it was seen on
+		if len(res.extracted) > 0 {
+			rules := make([]string, 0, len(res.extracted))
+			for rule := range res.extracted {
+				rules = append(rules, rule)
+			}
+			sort.Strings(rules)
+			for _, rule := range rules {
+				values := res.extracted[rule]
+				appendToFile(logPath, fmt.Sprintf("[+] EXTRACTED %s %s: %s", res.target, rule, strings.Join(values, ", ")))
+				for _, v := range claimExtracted(rule, values) {
+					appendToFile(filepath.Join(outputDir, extractedDirName, rule+".txt"), v+" "+res.target)
+				}
+				if !resQuiet {
+					fmt.Printf("%s      ↳ extracted %s: %d%s\n", ColorAccent, rule, len(values), ColorReset)
+				}
+			}
+		}
+
+		// --- JavaScript Discovery ---
+		if res.js != nil {
+			for _, err := range res.js.errs {
+				appendToFile(logPath, fmt.Sprintf("[!] JS Fetch Error for %s: %v", res.js.target, err))
+			}
+			for _, ep := range res.js.sortedEndpoints() {
+				appendToFile(jsEndpointsPath, fmt.Sprintf("[endpoint] %s (%q in %s)", ep.resolved, ep.raw, ep.script))
+			}
+			for _, host := range res.js.hosts {
+				appendToFile(jsEndpointsPath, fmt.Sprintf("[host] %s (referenced from %s)", host, res.js.target))
+			}
+			for _, sec := range res.js.secrets {
+				msg := fmt.Sprintf("[%s] %s (in %s)", sec.rule, sec.value, sec.script)
+				appendToFile(jsSecretsPath, msg)
+				appendToFile(logPath, "[!] JS SECRET "+msg)
+				// Always printed, like other high-value findings
+				fmt.Printf("%s[JS SECRET]%s %s -> %s%s%s (%s)\n", ColorFinding, ColorReset, res.js.target, ColorWarning, sec.rule, ColorReset, sec.script)
+			}
+			if !resQuiet && (len(res.js.endpoints) > 0 || len(res.js.scripts) > 0) {
+				fmt.Printf("%s      ↳ js: %d script(s), %d endpoint(s), %d host(s)%s\n", ColorAccent,
+					len(res.js.scripts), len(res.js.endpoints), len(res.js.hosts), ColorReset)
+			}
+		}
+
+		// --- robots.txt / Sitemap / security.txt Harvest ---
+		if res.harvest != nil {
+			appendToFile(harvestPath, res.harvest.jsonLine())
+			for _, err := range res.harvest.Errors {
+				appendToFile(logPath, fmt.Sprintf("[!] Harvest Error for %s: %s", res.harvest.Origin, err))
+			}
+			for _, sitemapURL := range res.harvest.OffOrigin {
+				appendToFile(logPath, fmt.Sprintf("[*] Harvest: %s lists off-origin sitemap %s (not fetched)", res.harvest.Origin, sitemapURL))
+			}
+			if !resQuiet {
+				disallowed := 0
+				if res.harvest.Robots != nil {
+					disallowed = len(res.harvest.Robots.Disallow)
+				}
+				fmt.Printf("%s      ↳ harvest: %d disallowed path(s), %d sitemap URL(s), security.txt=%t%s\n", ColorAccent,
+					disallowed, len(res.harvest.SitemapURLs), res.harvest.SecurityTxt != nil, ColorReset)
+			}
+		}
+
+		// --- YAML Template Findings ---
+		if res.templates != nil {
+			for _, err := range res.templates.errs {
+				appendToFile(logPath, fmt.Sprintf("[!] Template Error for %s: %v", res.templates.target, err))
+			}
+			for _, f := range res.templates.findings {
+				msg := describeFinding(f)
+				appendToFile(templateFindingsPath, msg)
+				appendToFile(logPath, "[!] TEMPLATE "+msg)
+				if !resQuiet || severityRank(f.severity) <= severityRank("high") {
+					fmt.Printf("%s[%s %s]%s %s %s(%s)%s\n", ColorFinding, strings.ToUpper(f.severity), f.templateID, ColorReset,
+						f.url, ColorWarning, f.name, ColorReset)
+				}
+			}
+			recordTemplateFindings(res.templates.findings)
+		}
+
+		// --- Favicon Hash Recording ---
+		if opts.faviconCheck && res.favicon != nil {
+			if res.favicon.err != nil {
+				// Missing icons are common; only log genuine fetch errors, don't print them
+				appendToFile(logPath, fmt.Sprintf("[!] Favicon Error for %s: %v", res.favicon.target, res.favicon.err))
+			} else if res.favicon.found {
+				appendToFile(faviconPath, fmt.Sprintf("%s %d %s %s %s",
+					res.favicon.target, res.favicon.mmh3, res.favicon.md5, res.favicon.sha256, res.favicon.iconURL))
+				recordFaviconHash(res.favicon.mmh3, res.favicon.target)
+				if !resQuiet {
+					fmt.Printf("%s      ↳ favicon mmh3=%d (%d bytes)%s\n", ColorAccent, res.favicon.mmh3, res.favicon.size, ColorReset)
+				}
+			}
+		}
+
+		// --- Crawled URLs, with the page that linked to them ---
+		if res.source == "crawl" {
+			outcome := fmt.Sprintf("%d", res.statusCode)
+			if res.err != nil {
+				outcome = "ERROR[" + res.errClass.String() + "]"
+			} else if filtered {
+				outcome += " FILTERED"
+			}
+			appendToFile(crawledPath, fmt.Sprintf("%s %s <- %s (depth %d)", res.target, outcome, res.parent, res.depth))
+		}
+
 		// Process primary scan result logic: update counters, manage failures, write files
 		if res.err != nil { // Handle Primary Scan Failure [source: 44]
 			logMsg := ""
+			recordErrorClass(res.target, res.errClass)
 			if !res.isRescan {
 				// --- Initial Scan Failure ---
 				atomic.AddInt64(failedScans, 1) // Increment overall fail count
@@ -91,29 +349,50 @@ func processResults(
 					failedTargetsMutex.Lock()
 					failedTargets = append(failedTargets, res.target) // Add to global list
 					failedTargetsMutex.Unlock()
+					tagInputSource(res.target, res.input) // So a re-scan keeps its label (inputs.go)
 					// Write to the invalid list only on the first failure
 					appendToFile(invalidPath, res.target) // [source: 44]
+					appendToFile(filepath.Join(outputDir, errorsDirName, res.errClass.String()+".txt"), res.target)
+				}
+				logMsg = fmt.Sprintf("[!] FAIL %s -> ERROR [%s]: %v", res.target, res.errClass, res.err)
+				if res.dns != nil {
+					logMsg += fmt.Sprintf(" (DNS: %s)", res.dns.status)
 				}
-				logMsg = fmt.Sprintf("[!] FAIL %s -> ERROR: %v", res.target, res.err)
 			} else {
 				// --- Re-scan Failure ---
 				// Failure persists after rescan
-				logMsg = fmt.Sprintf("[!!] RESCAN FAIL %s -> ERROR: %v", res.target, res.err) // [source: 45]
+				logMsg = fmt.Sprintf("[!!] RESCAN FAIL %s -> ERROR [%s]: %v", res.target, res.errClass, res.err) // [source: 45]
 				// Do not increment failedScans again, it was already counted during initial fail
 			}
-			appendToFile(logPath, logMsg) // Log the failure
+			appendToFile(logPath, logMsg+describeInputSource(res.input)) // Log the failure
 
-		} else { // Handle Primary Scan Success
+		} else if !filtered && !blocked { // Handle Primary Scan Success
 			logMsg := ""
 			if res.isRescan {
 				// --- Success during Re-scan ---
 				// Target failed initially but succeeded on rescan. Adjust overall counts.
-				atomic.AddInt64(failedScans, -1)                                                          // Decrease overall fail count
+				atomic.AddInt64(failedScans, -1) // Decrease overall fail count
+				clearErrorClass(res.target)
 				logMsg = fmt.Sprintf("[✓✓] RESCAN SUCCESS %s -> %d %s", res.target, res.statusCode, desc) // [source: 45]
 			} else {
 				// --- Success during Initial Scan ---
 				logMsg = fmt.Sprintf("[✓] SUCCESS %s -> %d %s", res.target, res.statusCode, desc) // [source: 45]
 			}
+			if res.remoteAddr != "" {
+				logMsg += fmt.Sprintf(" [%s]", res.remoteAddr) // Pin the result to the IP that answered
+			}
+			if res.parent != "" {
+				logMsg += fmt.Sprintf(" (found via %s on %s, depth %d)", res.source, res.parent, res.depth)
+			}
+			if res.timing != nil {
+				logMsg += " " + res.timing.describe()
+				if recordTiming(res.timing, opts.slowThreshold) {
+					appendToFile(slowPath, fmt.Sprintf("%s %s", res.target, res.timing.describe()))
+					if !resQuiet {
+						fmt.Printf("%s      ↳ SLOW: %s%s\n", ColorWarning, res.timing.describe(), ColorReset)
+					}
+				}
+			}
 			// Increment overall success count regardless of initial/rescan success
 			atomic.AddInt64(successfulScans, 1) // [source: 45]
 
@@ -125,7 +404,7 @@ func processResults(
 			// Write to common success files (exist list and log)
 			// Only write to ip_exist.txt if it succeeded at least once (initial or rescan)
 			appendToFile(existPath, res.target) // [source: 46]
-			appendToFile(logPath, logMsg)
+			appendToFile(logPath, logMsg+describeInputSource(res.input))
 
 			// Write to specific status code file based on category
 			catDigit := res.statusCode / 100
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// robotsTxt is the part of a robots.txt file hxscanner cares about: the rules for
+// the "*" user agent and the Sitemap lines (which apply to every agent)
+type robotsTxt struct {
+	found    bool // robots.txt existed (200); an empty robotsTxt allows everything
+	disallow []robotsRule
+	allow    []robotsRule
+	sitemaps []string
+	// Every Disallow/Allow path regardless of user agent, deduplicated, for -harvest
+	allDisallow []string
+	allAllow    []string
+}
+
+// robotsRule is one Allow/Disallow path. Rules with the "*" and trailing "$" wildcards
+// are compiled to a regexp once, when robots.txt is parsed.
+type robotsRule struct {
+	path string
+	re   *regexp.Regexp // nil for plain prefix rules
+}
+
+// newRobotsRule compiles a rule's wildcards, if it has any
+func newRobotsRule(path string) robotsRule {
+	rule := robotsRule{path: path}
+	if !strings.ContainsAny(path, "*$") {
+		return rule
+	}
+	anchored := strings.HasSuffix(path, "$")
+	expr := "^" + strings.ReplaceAll(regexp.QuoteMeta(strings.TrimSuffix(path, "$")), `\*`, ".*")
+	if anchored {
+		expr += "$"
+	}
+	rule.re = regexp.MustCompile(expr) // Quoted apart from ".*", so always valid
+	return rule
+}
+
+// matches reports whether the rule applies to a path
+func (r robotsRule) matches(path string) bool {
+	if r.re == nil {
+		return strings.HasPrefix(path, r.path)
+	}
+	return r.re.MatchString(path)
+}
+
+// parseRobotsTxt parses robots.txt content. Rules are taken from groups whose
+// User-agent is "*"; consecutive User-agent lines share a group.
+func parseRobotsTxt(body string) *robotsTxt {
+	r := &robotsTxt{found: true}
+	seen := make(map[string]bool)
+	inStar := false   // Current group applies to "*"
+	inAgents := false // Still reading the User-agent lines that open a group
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		switch key {
+		case "user-agent":
+			if !inAgents {
+				inStar = false
+			}
+			inAgents = true
+			if value == "*" {
+				inStar = true
+			}
+		case "disallow", "allow":
+			inAgents = false
+			if value == "" {
+				continue // An empty Disallow allows everything
+			}
+			if !seen[key+value] {
+				seen[key+value] = true
+				if key == "disallow" {
+					r.allDisallow = append(r.allDisallow, value)
+				} else {
+					r.allAllow = append(r.allAllow, value)
+				}
+			}
+			if !inStar {
+				continue
+			}
+			if key == "disallow" {
+				r.disallow = append(r.disallow, newRobotsRule(value))
+			} else {
+				r.allow = append(r.allow, newRobotsRule(value))
+			}
+		case "sitemap":
+			if value != "" {
+				r.sitemaps = append(r.sitemaps, value)
+			}
+		default:
+			inAgents = false
+		}
+	}
+	return r
+}
+
+// allowed applies the longest matching rule to a path (with query), Allow winning
+// ties, as Google and RFC 9309 do. Paths no rule matches are allowed.
+func (r *robotsTxt) allowed(path string) bool {
+	best, allow := -1, true
+	for _, rule := range r.disallow {
+		if rule.matches(path) && len(rule.path) > best {
+			best, allow = len(rule.path), false
+		}
+	}
+	for _, rule := range r.allow {
+		if rule.matches(path) && len(rule.path) >= best {
+			best, allow = len(rule.path), true
+		}
+	}
+	return allow
+}
+
+// --- Per-origin robots.txt cache ---
+type robotsCacheEntry struct {
+	once   sync.Once
+	robots *robotsTxt // Empty (allow everything) when robots.txt is missing or unreadable
+}
+
+var robotsCache = make(map[string]*robotsCacheEntry) // Keyed by scheme://host
+var robotsCacheMutex sync.Mutex
+
+// robotsFor returns the robots.txt rules for a URL's origin, fetching them once per origin
+func robotsFor(u *url.URL, client *http.Client, reqCfg *requestConfig) *robotsTxt {
+	origin := u.Scheme + "://" + u.Host
+	robotsCacheMutex.Lock()
+	entry, ok := robotsCache[origin]
+	if !ok {
+		entry = &robotsCacheEntry{}
+		robotsCache[origin] = entry
+	}
+	robotsCacheMutex.Unlock()
+
+	entry.once.Do(func() {
+		entry.robots = &robotsTxt{}
+		if body, err := fetchRobotsTxt(origin, client, reqCfg); err == nil {
+			entry.robots = parseRobotsTxt(body)
+		}
+	})
+	return entry.robots
+}
+
+// fetchRobotsTxt downloads /robots.txt for an origin; anything but a 200 is an error
+func fetchRobotsTxt(origin string, client *http.Client, reqCfg *requestConfig) (string, error) {
+	robotsURL := origin + "/robots.txt"
+	req, err := http.NewRequest("GET", robotsURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create robots.txt request for %s: %w", origin, err)
+	}
+	reqCfg.apply(req, "HyperScanner/1.4+Robots")
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("robots.txt request failed for %s: %w", origin, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("robots.txt for %s returned status %d", origin, resp.StatusCode)
+	}
+	return string(body), nil
+}
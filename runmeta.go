@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// runMetadata is written to run.json when a run starts and again when it ends, so an
+// interrupted run still records how it was started (end_time is then missing)
+type runMetadata struct {
+	Version      string            `json:"version"`
+	CommandLine  string            `json:"command_line"` // Shell-quoted, ready to paste
+	Args         []string          `json:"args"`
+	StartTime    time.Time         `json:"start_time"`
+	EndTime      *time.Time        `json:"end_time,omitempty"`
+	Duration     string            `json:"duration,omitempty"`
+	OutputDir    string            `json:"output_dir"`
+	OutputMode   string            `json:"output_mode"` // "new", "overwrite", "append" or "timestamped"
+	Inputs       []string          `json:"inputs"`
+	Options      map[string]string `json:"options"` // Every flag with its effective value
+	Results      *runResults       `json:"results,omitempty"`
+	PreviousRuns []runMetadata     `json:"previous_runs,omitempty"` // Earlier runs in this directory (-append)
+}
+
+// runResults are the final counts of a run
+type runResults struct {
+	Targets    int   `json:"targets"`
+	Discovered int   `json:"discovered"`
+	Successful int64 `json:"successful"`
+	Failed     int64 `json:"failed"`
+}
+
+// newRunMetadata describes the current process. In append mode the run.json already
+// in dir is kept as history.
+func newRunMetadata(dir, mode string, start time.Time, sources []inputSource) *runMetadata {
+	if mode == outputModeNew {
+		mode = "new"
+	}
+	meta := &runMetadata{
+		Version:     version,
+		CommandLine: shellJoin(os.Args),
+		Args:        os.Args[1:],
+		StartTime:   start,
+		OutputDir:   dir,
+		OutputMode:  mode,
+		Options:     make(map[string]string),
+	}
+	for _, src := range sources {
+		meta.Inputs = append(meta.Inputs, src.name())
+	}
+	flag.VisitAll(func(f *flag.Flag) {
+		if f.Name == "f" {
+			return // Shares its value with -i
+		}
+		meta.Options[f.Name] = f.Value.String()
+	})
+	if mode == outputModeAppend {
+		if data, err := os.ReadFile(filepath.Join(dir, runMetadataFileName)); err == nil {
+			var previous runMetadata
+			if json.Unmarshal(data, &previous) == nil {
+				meta.PreviousRuns = append(previous.PreviousRuns, previous)
+				meta.PreviousRuns[len(meta.PreviousRuns)-1].PreviousRuns = nil
+			}
+		}
+	}
+	return meta
+}
+
+// write saves the metadata to <dir>/run.json
+func (m *runMetadata) write() error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(m.OutputDir, runMetadataFileName), append(data, '\n'), 0644)
+}
+
+// finish records the end time and final counts and rewrites run.json
+func (m *runMetadata) finish(results runResults) {
+	end := time.Now()
+	m.EndTime = &end
+	m.Duration = end.Sub(m.StartTime).Round(time.Millisecond).String()
+	m.Results = &results
+	if err := m.write(); err != nil {
+		fmt.Fprintf(os.Stderr, "%sWarning: failed to write %s: %v%s\n", ColorWarning, runMetadataFileName, err, ColorReset)
+	}
+}
+
+// shellJoin quotes arguments that a POSIX shell would otherwise split or expand
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		if a != "" && !strings.ContainsAny(a, " \t\n'\"\\$`!*?[]{}()<>|&;#~") {
+			quoted[i] = a
+			continue
+		}
+		quoted[i] = "'" + strings.ReplaceAll(a, "'", `'\''`) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
@@ -1,16 +1,21 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
-	"strings"
 	"sync"
 	"time"
 )
 
-// setupHTTPClient creates the shared HTTP client for initial GET requests
-func setupHTTPClient(timeout time.Duration, workers int) *http.Client {
+// setupHTTPClient creates the shared HTTP client for initial GET requests.
+// A non-nil resolver replaces the system resolver for every connection the client makes
+// (a nil Resolver on the dialer means the system one).
+func setupHTTPClient(timeout time.Duration, workers int, resolver *net.Resolver) *http.Client {
 	// Transport settings optimized for potentially many connections
 	transport := &http.Transport{
 		Proxy:                 http.ProxyFromEnvironment,
@@ -24,6 +29,11 @@ func setupHTTPClient(timeout time.Duration, workers int) *http.Client {
 		// Add TLSClientConfig if needed globally, e.g., for InsecureSkipVerify
 		// TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
 	}
+	// The dial deadline is a little shorter than the whole request's, so a DNS or connect
+	// timeout fails with the dialer's own error (dns_timeout/tcp_timeout in errclass.go)
+	// instead of the client's generic "Client.Timeout exceeded".
+	dialer := &net.Dialer{Timeout: timeout * 9 / 10, KeepAlive: 30 * time.Second, Resolver: resolver}
+	transport.DialContext = dialer.DialContext
 
 	return &http.Client{
 		Timeout:   timeout,
@@ -35,53 +45,43 @@ func setupHTTPClient(timeout time.Duration, workers int) *http.Client {
 	}
 }
 
-// scanTarget performs the primary HTTP GET request for a target
-func scanTarget(target string, client *http.Client) (int, error) {
-	urlToScan := target
-	// Prepend http:// if no scheme is present (handles IPs and domains)
-	if !strings.Contains(target, "://") {
-		// Check if it looks like an IPv6 address that needs brackets
-		if strings.Contains(target, ":") && !strings.HasPrefix(target, "[") && !strings.HasSuffix(target, "]") {
-			// Basic check, might need refinement for edge cases
-			isIPv6 := false
-			parts := strings.Split(target, ":")
-			if len(parts) > 2 { // Simple heuristic for IPv6
-				isIPv6 = true
-			}
-			// Check if it's likely host:port
-			if len(parts) == 2 {
-				_, portErr := url.Parse("http://dummy:" + parts[1]) // Check if part after : is a valid port
-				if portErr != nil {                                 // If not a valid port, assume IPv6
-					isIPv6 = true
-				}
-			}
+// maxBodySize caps how much of a response body is kept for later checks
+const maxBodySize = 1 << 20 // 1 MiB
 
-			if isIPv6 {
-				urlToScan = "http://[" + target + "]"
-			} else {
-				urlToScan = "http://" + target
-			}
-		} else {
-			urlToScan = "http://" + target
-		}
-	} // [source: 48]
+// probeResponse holds the parts of the primary response that later checks need
+type probeResponse struct {
+	url        *url.URL // URL that was actually requested
+	statusCode int
+	header     http.Header
+	body       []byte // Truncated to maxBodySize
+	remoteAddr string // IP:port the response actually came from
+	timing     *probeTiming
+}
 
-	// Validate the final URL structure before making the request
-	parsedURL, err := url.ParseRequestURI(urlToScan)
+// scanTarget performs the primary HTTP request for a target (GET unless -X/-d say otherwise)
+func scanTarget(target string, client *http.Client, reqCfg *requestConfig) (*probeResponse, error) {
+	parsed, err := ParseTarget(target) // From target.go
 	if err != nil {
-		// Return an error if the URL format is fundamentally invalid after scheme prepending
-		return 0, fmt.Errorf("invalid target format '%s' -> '%s': %w", target, urlToScan, err) // [source: 48]
+		return nil, err
 	}
-	urlToScan = parsedURL.String() // Use the validated URL string
+	urlToScan := parsed.String()
 
 	// Create request (defaulting to GET)
-	req, err := http.NewRequest("GET", urlToScan, nil)
+	method := "GET"
+	if reqCfg != nil {
+		method = reqCfg.method
+	}
+	req, err := http.NewRequest(method, urlToScan, reqCfg.bodyReader())
 	if err != nil {
 		// This error is less likely if url.Parse succeeded, but check anyway
-		return 0, fmt.Errorf("failed to create GET request for %s: %w", urlToScan, err) // [source: 49]
+		return nil, fmt.Errorf("failed to create %s request for %s: %w", method, urlToScan, err) // [source: 49]
 	}
-	// Set a distinct user agent for the main scanner
-	req.Header.Set("User-Agent", "HyperScanner/1.4") // [source: 49]
+	// Set a distinct user agent for the main scanner, plus any -H/-cookie/-ua customisation
+	reqCfg.apply(req, defaultUserAgent) // [source: 49]
+
+	// Trace the request for per-phase timing and the IP we actually connected to
+	recorder, trace := newTimingRecorder() // From timing.go
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
 
 	// Perform the request using the provided shared client
 	resp, err := client.Do(req)
@@ -92,46 +92,194 @@ func scanTarget(target string, client *http.Client) (int, error) {
 		// if ok && urlErr.Timeout() {
 		//  return 0, fmt.Errorf("timeout reaching %s: %w", urlToScan, err)
 		// }
-		return 0, fmt.Errorf("request failed for %s: %w", urlToScan, err) // Return wrapped error
+		return nil, fmt.Errorf("request failed for %s: %w", urlToScan, err) // Return wrapped error
 	}
 	// Ensure the response body is always closed to free up resources
 	defer resp.Body.Close() // [source: 49]
 
-	// Return the status code from the response
-	return resp.StatusCode, nil // [source: 49]
+	// Keep a bounded copy of the body for checks that inspect page content.
+	// A read error here is not a scan failure; the status code is still valid.
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	timing, remoteAddr := recorder.finish()
+
+	return &probeResponse{
+		url:        req.URL,
+		statusCode: resp.StatusCode,
+		header:     resp.Header,
+		body:       body,
+		remoteAddr: remoteAddr,
+		timing:     timing,
+	}, nil
 }
 
-// worker executes scan jobs received from the jobs channel
-// Added corsCheck flag parameter
-func worker(id int, wg *sync.WaitGroup, client *http.Client, jobs <-chan string, results chan<- scanResult, isRescan bool, corsCheck bool) {
+// worker executes scan jobs received from the queue
+func worker(id int, wg *sync.WaitGroup, client *http.Client, queue *jobQueue, results chan<- scanResult, isRescan bool, opts *scanOptions) {
 	defer wg.Done() // Signal completion when channel is closed and loop finishes
-	for target := range jobs {
-		if target == "" { // Skip empty lines [source: 50]
-			continue
+	for job := range queue.jobs {
+		if job.target != "" { // Skip empty lines [source: 50]
+			// Send the combined result (including potential CORS info) back to the results processor
+			results <- scanJobTarget(job, client, queue, isRescan, opts) // [source: 50]
+		}
+		queue.done() // Only after the result is sent, so discovered jobs are already counted
+	}
+}
+
+// scanJobTarget runs the primary probe and every enabled check for one job.
+// Checks that discover new URLs add them to the queue.
+func scanJobTarget(job scanJob, client *http.Client, queue *jobQueue, isRescan bool, opts *scanOptions) scanResult {
+	target := job.target
+
+	// With -waf, a host that throttled an earlier request is left alone until its backoff ends
+	var hostKey string
+	if opts.wafCheck {
+		hostKey = wafHostKey(target) // From waf.go
+		waitForWAFBackoff(hostKey)
+	}
+
+	// Perform the primary request: the -raw template if one was given, otherwise a normal request
+	var resp *probeResponse
+	var err error
+	if opts.raw != nil {
+		// Raw requests bypass the HTTP client, so -scope is checked here instead of in its transport
+		if reason := opts.scope.checkTarget(target); reason != "" {
+			opts.scope.reject(target, "request", reason)
+			err = fmt.Errorf("%w: %s (%s)", errOutOfScope, target, reason)
+		} else {
+			resp, err = sendRawRequest(target, opts.raw) // From rawrequest.go
+		}
+	} else {
+		resp, err = scanTarget(target, client, opts.request)
+	}
+
+	// Prepare the basic result struct
+	result := scanResult{
+		target:   target,
+		err:      err,
+		isRescan: isRescan,
+		cors:     nil, // Initialize CORS result pointer to nil
+		parent:   job.parent,
+		depth:    job.depth,
+		source:   job.source,
+		input:    job.input,
+	}
+	if resp != nil {
+		result.statusCode = resp.statusCode
+		result.remoteAddr = resp.remoteAddr
+		result.timing = resp.timing
+		result.url = resp.url.String()
+		result.length = len(resp.body)
+		result.title = extractTitle(resp.body)           // From filters.go
+		result.tech = detectTech(resp.header, resp.body) // From report.go
+	}
+
+	// --- Record DNS answers for the target's hostname ---
+	if opts.dnsRecords {
+		if parsed, parseErr := ParseTarget(target); parseErr == nil && parsed.Kind == HostDomain {
+			result.dns = opts.resolver.lookup(context.Background(), parsed.Host)
+		}
+	}
+
+	// Classify failures now that DNS answers (if any) are available to disambiguate
+	result.errClass = classifyError(err, result.dns)
+
+	// --- Match/filter options ---
+	// A filtered result is kept out of the terminal and status files, but every enabled
+	// check still runs: a filtered 404 can still be a takeover or leak a secret
+	if opts.filter != nil && err == nil && resp != nil {
+		result.filtered = opts.filter.check(resp)
+	}
+
+	// --- WAF/CDN fingerprinting ---
+	// Runs before the other checks: if the WAF is throttling us, they wait out the backoff
+	if opts.wafCheck && err == nil && resp != nil {
+		wafRes := checkWAF(target, resp, client, opts.wafProbe)
+		if wafRes.throttled = startWAFBackoff(hostKey, resp, &wafRes); wafRes.throttled > 0 {
+			waitForWAFBackoff(hostKey)
 		}
+		result.waf = &wafRes
+	}
 
-		status, err := scanTarget(target, client) // Perform the initial GET scan
+	// --- Perform CORS Check if enabled AND initial scan was successful ---
+	// Also ensure status code is not 0 (which indicates an error in scanTarget itself)
+	if opts.corsCheck && err == nil && result.statusCode != 0 {
+		// Perform the CORS check (function defined in cors.go)
+		// Pass the same target and the main client (checkCORS uses its own internal client settings)
+		corsResult := checkCORS(target, client, opts.request)
+		result.cors = &corsResult // Store the pointer to the CORS result in the main scanResult
+	}
+	// --- End CORS Check ---
 
-		// Prepare the basic result struct
-		result := scanResult{
-			target:     target,
-			statusCode: status,
-			err:        err,
-			isRescan:   isRescan,
-			cors:       nil, // Initialize CORS result pointer to nil
+	// --- Risky HTTP method enumeration ---
+	if opts.methodsCheck && err == nil && resp != nil {
+		methodsRes := checkMethods(target, resp, client)
+		result.methods = &methodsRes
+	}
+
+	// --- CRLF / header injection ---
+	if opts.crlfCheck && err == nil && resp != nil {
+		crlfRes := checkCRLF(target, resp, client)
+		result.crlf = &crlfRes
+	}
+
+	// --- Web cache poisoning via unkeyed headers ---
+	if opts.cacheCheck && err == nil && resp != nil {
+		cacheRes := checkCachePoisoning(target, resp, client)
+		result.cache = &cacheRes
+	}
+
+	// --- Subdomain takeover ---
+	// Runs for failed targets too: a dangling CNAME often doesn't resolve at all
+	if opts.takeover {
+		takeoverRes := checkTakeover(target, resp, opts.resolver)
+		result.takeover = &takeoverRes
+	}
+
+	// --- Data extraction from the primary response ---
+	if len(opts.extractRules) > 0 && err == nil && resp != nil {
+		result.extracted = extractData(resp, opts.extractRules) // From extract.go
+	}
+
+	// --- JavaScript endpoint and secret discovery ---
+	if opts.jsCheck && err == nil && resp != nil {
+		jsRes := checkJS(target, resp, client, opts.request) // From jsdiscovery.go
+		result.js = &jsRes
+		if opts.jsEnqueue && job.depth < opts.jsDepth {
+			for _, ep := range jsRes.followUpTargets(resp.url) {
+				queue.enqueue(scanJob{target: ep.resolved, parent: ep.script, depth: job.depth + 1, source: "js", input: job.input})
+			}
 		}
+	}
+
+	// --- Same-origin crawling ---
+	if opts.crawl && err == nil && resp != nil && job.depth < opts.crawlDepth {
+		crawlPage(resp, job, client, queue, opts) // From crawl.go
+	}
 
-		// --- Perform CORS Check if enabled AND initial scan was successful ---
-		// Also ensure status code is not 0 (which indicates an error in scanTarget itself)
-		if corsCheck && err == nil && status != 0 {
-			// Perform the CORS check (function defined in cors.go)
-			// Pass the same target and the main client (checkCORS uses its own internal client settings)
-			corsResult := checkCORS(target, client)
-			result.cors = &corsResult // Store the pointer to the CORS result in the main scanResult
+	// --- robots.txt / sitemap / security.txt harvesting, once per origin ---
+	if opts.harvest && err == nil && resp != nil && claimHarvest(resp.url.Scheme+"://"+resp.url.Host) {
+		harvestRes := harvestHost(target, resp, client, opts.request) // From harvest.go
+		result.harvest = &harvestRes
+		if opts.harvestQueue {
+			for _, next := range harvestRes.followUpTargets() {
+				next.parent = harvestRes.Origin
+				next.depth = job.depth + 1
+				next.input = job.input
+				queue.enqueue(next)
+			}
 		}
-		// --- End CORS Check ---
+	}
 
-		// Send the combined result (including potential CORS info) back to the results processor
-		results <- result // [source: 50]
+	// --- YAML template checks ---
+	if len(opts.templates) > 0 && err == nil && resp != nil {
+		templateRes := runTemplates(target, resp, client, opts.request, opts.templates) // From templates.go
+		result.templates = &templateRes
 	}
+
+	// --- Favicon hashing for live hosts ---
+	if opts.faviconCheck && err == nil && resp != nil {
+		favResult := checkFavicon(target, resp, client)
+		result.favicon = &favResult
+	}
+
+	return result
 }
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// errOutOfScope is returned (wrapped) for any request or target the -scope file excludes
+var errOutOfScope = errors.New("target is out of scope")
+
+// scopeCommentPattern strips trailing comments; the leading whitespace keeps "#" usable in regexes
+var scopeCommentPattern = regexp.MustCompile(`\s+#.*$`)
+
+// scopeRule is one line of a scope file
+type scopeRule struct {
+	text     string         // The line as written, for out_of_scope.txt
+	host     string         // Exact host ("example.com")
+	wildcard string         // Suffix for "*.example.com" rules (".example.com")
+	prefix   netip.Prefix   // CIDR or single IP
+	regex    *regexp.Regexp // "re:" rules, matched against the full URL
+}
+
+// scopeRules decides which URLs hxscanner may touch. A URL is in scope when it matches
+// at least one include rule (or there are none) and no exclude rule.
+type scopeRules struct {
+	includes []scopeRule
+	excludes []scopeRule
+	logPath  string // out_of_scope.txt; set once the output directory exists
+}
+
+// --- Out-of-scope bookkeeping (shared by all phases) ---
+var outOfScopeReported = make(map[string]bool) // Out-of-scope items already logged
+var outOfScopeCounts = make(map[string]int)    // Out-of-scope items per stage ("input", "follow-up", "request")
+var outOfScopeMutex sync.Mutex
+
+// loadScope parses a scope file. One rule per line:
+//
+//	example.com          exact host
+//	*.example.com        any subdomain (not the apex; list it separately)
+//	10.0.0.0/8, 1.2.3.4  CIDR or single IP (IP targets only)
+//	re:<regex>           regular expression matched against the full URL
+//	!<rule>              exclude; excludes always win
+//
+// Blank lines, # comments and trailing " # comments" are ignored.
+func loadScope(path string) (*scopeRules, error) {
+	lines, err := readTargetsFromFile(path) // From utils.go
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scope file %s: %w", path, err)
+	}
+	s := &scopeRules{}
+	for i, line := range lines {
+		line = strings.TrimSpace(scopeCommentPattern.ReplaceAllString(line, ""))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		exclude := strings.HasPrefix(line, "!")
+		rule, err := parseScopeRule(strings.TrimSpace(strings.TrimPrefix(line, "!")))
+		if err != nil {
+			return nil, fmt.Errorf("scope file %s line %d: %w", path, i+1, err)
+		}
+		rule.text = line
+		if exclude {
+			s.excludes = append(s.excludes, rule)
+		} else {
+			s.includes = append(s.includes, rule)
+		}
+	}
+	if len(s.includes) == 0 && len(s.excludes) == 0 {
+		return nil, fmt.Errorf("scope file %s has no rules", path)
+	}
+	return s, nil
+}
+
+// parseScopeRule parses a single rule (without the "!" prefix)
+func parseScopeRule(text string) (scopeRule, error) {
+	switch {
+	case strings.HasPrefix(text, "re:"):
+		re, err := regexp.Compile(strings.TrimPrefix(text, "re:"))
+		if err != nil {
+			return scopeRule{}, fmt.Errorf("invalid regex %q: %w", text, err)
+		}
+		return scopeRule{regex: re}, nil
+	case strings.Contains(text, "/"):
+		prefix, err := netip.ParsePrefix(text)
+		if err != nil {
+			return scopeRule{}, fmt.Errorf("invalid CIDR %q: %w", text, err)
+		}
+		return scopeRule{prefix: prefix.Masked()}, nil
+	case strings.HasPrefix(text, "*."):
+		return scopeRule{wildcard: strings.ToLower(strings.TrimPrefix(text, "*"))}, nil
+	}
+	if addr, err := netip.ParseAddr(strings.Trim(text, "[]")); err == nil {
+		return scopeRule{prefix: netip.PrefixFrom(addr, addr.BitLen())}, nil
+	}
+	if strings.ContainsAny(text, "*:") {
+		return scopeRule{}, fmt.Errorf("unsupported rule %q (use example.com, *.example.com, a CIDR or re:<regex>)", text)
+	}
+	return scopeRule{host: strings.ToLower(strings.TrimSuffix(text, "."))}, nil
+}
+
+// matches checks one rule against a parsed URL
+func (r scopeRule) matches(u *url.URL) bool {
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	switch {
+	case r.regex != nil:
+		return r.regex.MatchString(u.String())
+	case r.prefix.IsValid():
+		addr, err := netip.ParseAddr(host)
+		return err == nil && r.prefix.Contains(addr.Unmap())
+	case r.wildcard != "":
+		return strings.HasSuffix(host, r.wildcard)
+	default:
+		return host == r.host
+	}
+}
+
+// check returns "" for an in-scope URL, or the reason it is out of scope.
+// A nil *scopeRules (no -scope file) accepts everything.
+func (s *scopeRules) check(u *url.URL) string {
+	if s == nil {
+		return ""
+	}
+	for _, r := range s.excludes {
+		if r.matches(u) {
+			return "excluded by " + r.text
+		}
+	}
+	if len(s.includes) == 0 {
+		return ""
+	}
+	for _, r := range s.includes {
+		if r.matches(u) {
+			return ""
+		}
+	}
+	return "matches no include rule"
+}
+
+// checkTarget is check for a raw target string; unparseable targets are left to the scanner
+func (s *scopeRules) checkTarget(target string) string {
+	if s == nil {
+		return ""
+	}
+	parsed, err := ParseTarget(target) // From target.go
+	if err != nil {
+		return ""
+	}
+	return s.check(parsed.URL())
+}
+
+// reject logs an out-of-scope item to out_of_scope.txt (once per item) and counts it
+func (s *scopeRules) reject(item, stage, reason string) {
+	outOfScopeMutex.Lock()
+	first := !outOfScopeReported[item]
+	outOfScopeReported[item] = true
+	if first {
+		outOfScopeCounts[stage]++
+	}
+	outOfScopeMutex.Unlock()
+	if first && s.logPath != "" {
+		appendToFile(s.logPath, fmt.Sprintf("%s [%s] %s", item, stage, reason))
+	}
+}
+
+// partition splits input targets into in-scope ones and rejects (logged as "input").
+// The kept targets go to a new list stored like the old one, which is closed.
+func (s *scopeRules) partition(targets *targetList) (*targetList, error) {
+	kept, err := targets.emptyLike()
+	if err != nil {
+		return nil, err
+	}
+	var addErr error
+	err = targets.each(func(t, label string) {
+		if reason := s.checkTarget(t); reason != "" {
+			s.reject(t, "input", reason)
+		} else if addErr == nil {
+			addErr = kept.add(t, label)
+		}
+	})
+	targets.close()
+	if err == nil {
+		err = addErr
+	}
+	if err != nil {
+		kept.close()
+		return nil, fmt.Errorf("failed to filter the target list: %w", err)
+	}
+	return kept, nil
+}
+
+// printScopeSummary shows how many items scope enforcement stopped, per stage
+func printScopeSummary() {
+	outOfScopeMutex.Lock()
+	defer outOfScopeMutex.Unlock()
+	total := 0
+	for _, n := range outOfScopeCounts {
+		total += n
+	}
+	if total == 0 {
+		return
+	}
+	fmt.Printf("\n%sOut of Scope: %d%s\n", ColorWarning, total, ColorReset)
+	for _, stage := range []string{"input", "follow-up", "request"} {
+		if n := outOfScopeCounts[stage]; n > 0 {
+			fmt.Printf("  %-25s : %d\n", stage, n)
+		}
+	}
+}
+
+// scopedTransport refuses to send requests outside the scope, so every check, redirect
+// follow-up and fetch made through the client is covered, not just the primary probe
+type scopedTransport struct {
+	next  http.RoundTripper
+	scope *scopeRules
+}
+
+func (t *scopedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if reason := t.scope.check(req.URL); reason != "" {
+		t.scope.reject(req.URL.String(), "request", reason)
+		return nil, fmt.Errorf("%w: %s (%s)", errOutOfScope, req.URL, reason)
+	}
+	return t.next.RoundTrip(req)
+}
+
+// withClientScope wraps rt in the same scope enforcement as client, if it has any.
+// Checks that build their own transport (e.g. checkCORS) use it to stay in scope.
+func withClientScope(client *http.Client, rt http.RoundTripper) http.RoundTripper {
+	if st, ok := client.Transport.(*scopedTransport); ok {
+		return &scopedTransport{next: rt, scope: st.scope}
+	}
+	return rt
+}
+
+// baseTransport returns the client's *http.Transport, looking through scope enforcement
+func baseTransport(client *http.Client) (*http.Transport, bool) {
+	rt := client.Transport
+	if st, ok := rt.(*scopedTransport); ok {
+		rt = st.next
+	}
+	t, ok := rt.(*http.Transport)
+	return t, ok
+}
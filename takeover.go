@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// takeoverFingerprint describes a service whose dangling CNAMEs can be claimed
+type takeoverFingerprint struct {
+	service  string
+	cnames   []string // Suffixes matched against every name in the CNAME chain
+	body     []string // Substrings of the "unclaimed" page served by the service
+	nxdomain bool     // A CNAME into this service that no longer resolves is itself vulnerable
+}
+
+// takeoverFingerprints is the built-in database, based on the community
+// "can-i-take-over-xyz" list (services known to be claimable)
+var takeoverFingerprints = []takeoverFingerprint{
+	{service: "AWS S3", cnames: []string{"s3.amazonaws.com", ".s3-website", "s3-website-"}, body: []string{"NoSuchBucket", "The specified bucket does not exist"}},
+	{service: "GitHub Pages", cnames: []string{"github.io"}, body: []string{"There isn't a GitHub Pages site here."}},
+	{service: "Heroku", cnames: []string{"herokuapp.com", "herokudns.com", "herokussl.com"}, body: []string{"No such app", "herokucdn.com/error-pages/no-such-app.html"}},
+	{service: "Microsoft Azure", cnames: []string{"azurewebsites.net", "cloudapp.net", "cloudapp.azure.com", "trafficmanager.net", "blob.core.windows.net", "azureedge.net", "azure-api.net", "azurefd.net"}, body: []string{"404 Web Site not found"}, nxdomain: true},
+	{service: "AWS Elastic Beanstalk", cnames: []string{"elasticbeanstalk.com"}, nxdomain: true},
+	{service: "Shopify", cnames: []string{"myshopify.com"}, body: []string{"Sorry, this shop is currently unavailable."}},
+	{service: "Fastly", cnames: []string{"fastly.net"}, body: []string{"Fastly error: unknown domain"}},
+	{service: "Pantheon", cnames: []string{"pantheonsite.io"}, body: []string{"The gods are wise, but do not know of the site which you seek."}},
+	{service: "Tumblr", cnames: []string{"domains.tumblr.com"}, body: []string{"Whatever you were looking for doesn't currently exist at this address"}},
+	{service: "WordPress.com", cnames: []string{"wordpress.com"}, body: []string{"Do you want to register"}},
+	{service: "Zendesk", cnames: []string{"zendesk.com"}, body: []string{"Help Center Closed"}},
+	{service: "Surge.sh", cnames: []string{"surge.sh"}, body: []string{"project not found"}},
+	{service: "Bitbucket", cnames: []string{"bitbucket.io"}, body: []string{"Repository not found"}},
+	{service: "Ghost", cnames: []string{"ghost.io"}, body: []string{"The thing you were looking for is no longer here"}},
+	{service: "Netlify", cnames: []string{"netlify.app", "netlify.com"}, body: []string{"Not Found - Request ID"}},
+	{service: "Readme.io", cnames: []string{"readme.io"}, body: []string{"Project doesnt exist... yet!"}},
+	{service: "Webflow", cnames: []string{"proxy.webflow.com", "proxy-ssl.webflow.com"}, body: []string{"The page you are looking for doesn't exist or has been moved."}},
+	{service: "Agile CRM", cnames: []string{"agilecrm.com"}, body: []string{"Sorry, this page is no longer available."}},
+	{service: "Helpjuice", cnames: []string{"helpjuice.com"}, body: []string{"We could not find what you're looking for."}},
+	{service: "Help Scout", cnames: []string{"helpscoutdocs.com"}, body: []string{"No settings were found for this company:"}},
+	{service: "Strikingly", cnames: []string{"s.strikinglydns.com"}, body: []string{"PAGE NOT FOUND"}},
+	{service: "Unbounce", cnames: []string{"unbouncepages.com"}, body: []string{"The requested URL was not found on this server"}},
+}
+
+// takeoverResult holds the outcome of the takeover check for a target
+type takeoverResult struct {
+	target     string
+	host       string
+	cnameChain []string
+	rcode      dnsmessage.RCode // Response code for the final name in the chain
+	service    string           // Matched service ("" if none)
+	vulnerable bool             // CNAME matched and the body/NXDOMAIN signature confirmed it
+	severity   string           // "HIGH" when vulnerable
+	evidence   string
+	err        error
+}
+
+// checkTakeover resolves the target's CNAME chain and matches it, together with the
+// response body (if the host answered at all), against the fingerprint database.
+// mainResp may be nil: dangling CNAMEs frequently fail to resolve, which is exactly
+// the case the nxdomain fingerprints are for.
+func checkTakeover(target string, mainResp *probeResponse, resolver *dnsClient) takeoverResult {
+	result := takeoverResult{target: target}
+
+	parsed, err := ParseTarget(target) // From target.go
+	if err != nil {
+		result.err = err
+		return result
+	}
+	result.host = parsed.Host
+	if parsed.Kind != HostDomain {
+		return result // IPs have no CNAMEs
+	}
+
+	chain, rcode, err := resolver.cnameChain(context.Background(), result.host)
+	if err != nil {
+		result.err = fmt.Errorf("CNAME lookup failed for %s: %w", result.host, err)
+		return result
+	}
+	result.cnameChain = chain
+	result.rcode = rcode
+	if len(chain) == 0 {
+		return result
+	}
+
+	for _, fp := range takeoverFingerprints {
+		matchedName := ""
+		for _, name := range chain {
+			for _, suffix := range fp.cnames {
+				if strings.Contains(strings.ToLower(name), suffix) {
+					matchedName = name
+					break
+				}
+			}
+			if matchedName != "" {
+				break
+			}
+		}
+		if matchedName == "" {
+			continue
+		}
+
+		result.service = fp.service
+		if fp.nxdomain && rcode == dnsmessage.RCodeNameError {
+			result.vulnerable = true
+			result.evidence = fmt.Sprintf("CNAME %s does not resolve (NXDOMAIN)", matchedName)
+		} else if mainResp != nil {
+			for _, sig := range fp.body {
+				if strings.Contains(string(mainResp.body), sig) {
+					result.vulnerable = true
+					result.evidence = fmt.Sprintf("CNAME %s, body contains %q (status %d)", matchedName, sig, mainResp.statusCode)
+					break
+				}
+			}
+		}
+		if result.vulnerable {
+			result.severity = "HIGH"
+		} else {
+			result.evidence = fmt.Sprintf("CNAME %s points at %s but no unclaimed signature matched", matchedName, fp.service)
+		}
+		return result
+	}
+	return result
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// HostKind says what a target's host is
+type HostKind int
+
+const (
+	HostDomain HostKind = iota
+	HostIPv4
+	HostIPv6
+)
+
+func (k HostKind) String() string {
+	switch k {
+	case HostIPv4:
+		return "ipv4"
+	case HostIPv6:
+		return "ipv6"
+	default:
+		return "domain"
+	}
+}
+
+// zonePattern limits IPv6 zones to interface names and numeric indexes ("" = no zone)
+var zonePattern = regexp.MustCompile(`^[A-Za-z0-9._-]*$`)
+
+// defaultPorts are the ports each scheme implies
+var defaultPorts = map[string]string{"http": "80", "https": "443"}
+
+// Target is an input line (IP, domain, host:port or URL) split into its parts.
+// Every request hxscanner sends for an input target starts from ParseTarget.
+type Target struct {
+	Scheme   string   // "http" or "https" (lowercase; "http" when the line had none)
+	Host     string   // Hostname or IP without brackets or port; IPv6 zones use "%" ("fe80::1%eth0")
+	Kind     HostKind // Domain, IPv4 or IPv6
+	Port     string   // Port as written, "" when absent
+	Path     string   // Escaped path as written, "" when absent
+	Query    string   // Raw query without the "?" (spaces escaped), "" when absent
+	Original string   // The line as given
+	userinfo string   // "user:pass" before an "@", kept so such URLs still work
+}
+
+// ParseTarget parses an input line. Without a scheme, http is assumed. IPv6 addresses
+// may be bracketed ("[::1]:8080") or bare ("::1"); a bare address never has a port,
+// so "2001:db8::1:8080" is the address 2001:db8::1:8080. A single colon is always
+// host:port. Fragments are dropped. Errors wrap errInvalidTarget.
+func ParseTarget(line string) (Target, error) {
+	t := Target{Original: line}
+	fail := func(format string, args ...any) (Target, error) {
+		return Target{}, fmt.Errorf("%w '%s': %s", errInvalidTarget, line, fmt.Sprintf(format, args...))
+	}
+
+	s := strings.TrimSpace(line)
+	for i := 0; i < len(s); i++ {
+		if s[i] < 0x20 || s[i] == 0x7f {
+			return fail("control character at offset %d", i)
+		}
+	}
+	s, _, _ = strings.Cut(s, "#")
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return fail("empty target")
+	}
+
+	// --- Scheme ---
+	t.Scheme = "http"
+	if scheme, rest, ok := strings.Cut(s, "://"); ok {
+		t.Scheme = strings.ToLower(scheme)
+		if t.Scheme != "http" && t.Scheme != "https" {
+			return fail("unsupported scheme %q (only http and https)", scheme)
+		}
+		s = rest
+	}
+
+	// --- Authority vs path and query ---
+	authority := s
+	if i := strings.IndexAny(s, "/?"); i >= 0 {
+		authority, s = s[:i], s[i:]
+	} else {
+		s = ""
+	}
+	pathPart, query, hasQuery := strings.Cut(s, "?")
+	if _, err := url.PathUnescape(pathPart); err != nil {
+		return fail("invalid path escape: %v", err)
+	}
+	t.Path = pathPart
+	if hasQuery {
+		t.Query = strings.ReplaceAll(query, " ", "%20") // A raw space would split the request line
+	}
+
+	if i := strings.LastIndex(authority, "@"); i >= 0 {
+		t.userinfo, authority = authority[:i], authority[i+1:]
+		if _, err := url.Parse("http://" + t.userinfo + "@host/"); err != nil {
+			return fail("invalid credentials before '@'")
+		}
+	}
+	if authority == "" {
+		return fail("no host")
+	}
+
+	// --- Host and port ---
+	var host string
+	switch {
+	case strings.HasPrefix(authority, "["):
+		end := strings.Index(authority, "]")
+		if end < 0 {
+			return fail("missing ']' in IPv6 address")
+		}
+		host = authority[1:end]
+		rest := authority[end+1:]
+		if rest != "" {
+			if !strings.HasPrefix(rest, ":") {
+				return fail("unexpected %q after IPv6 address", rest)
+			}
+			t.Port = rest[1:]
+			if t.Port == "" {
+				return fail("empty port")
+			}
+		}
+		if unescaped, err := url.PathUnescape(host); err == nil {
+			host = unescaped // "%25" zone separator in URLs
+		}
+		addr, err := netip.ParseAddr(host)
+		if err != nil || !addr.Is6() {
+			return fail("%q is not an IPv6 address", host)
+		}
+	case strings.Count(authority, ":") > 1:
+		addr, err := netip.ParseAddr(authority)
+		if err != nil || !addr.Is6() {
+			return fail("%q is not an IPv6 address (bracket it to add a port)", authority)
+		}
+		host = authority
+	default:
+		var hasPort bool
+		host, t.Port, hasPort = strings.Cut(authority, ":")
+		if hasPort && t.Port == "" {
+			return fail("empty port")
+		}
+	}
+	if t.Port != "" {
+		n, err := strconv.Atoi(t.Port)
+		if err != nil || n < 1 || n > 65535 || strings.ContainsAny(t.Port, "+-") {
+			return fail("invalid port %q", t.Port)
+		}
+	}
+
+	// --- Host kind (only the branches above can yield IPv6) ---
+	t.Host = host
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if addr.Is4() {
+			t.Kind = HostIPv4
+		} else {
+			t.Kind = HostIPv6
+		}
+		if !zonePattern.MatchString(addr.Zone()) {
+			return fail("invalid IPv6 zone %q", addr.Zone())
+		}
+		return t, nil
+	}
+	if strings.Contains(host, "%") { // net/url escapes non-ASCII hosts ("b%C3%BCcher.de")
+		unescaped, err := url.PathUnescape(host)
+		if err != nil {
+			return fail("invalid escape in host %q", host)
+		}
+		host = unescaped
+		t.Host = host
+	}
+	if host == "" {
+		return fail("no host")
+	}
+	if strings.ContainsAny(host, " \"<>\\^`{|}%[]/?#@:") || strings.ContainsFunc(host, func(r rune) bool { return r < 0x20 || r == 0x7f }) {
+		return fail("invalid character in host %q", host)
+	}
+	if !validLabels(host) {
+		return fail("empty label in host %q", host)
+	}
+	t.Kind = HostDomain
+	return t, nil
+}
+
+// validLabels reports whether a domain has no empty labels (one trailing dot is allowed)
+func validLabels(host string) bool {
+	labels := strings.TrimSuffix(host, ".")
+	return labels != "" && !strings.HasPrefix(labels, ".") && !strings.HasSuffix(labels, ".") && !strings.Contains(labels, "..")
+}
+
+// hostPort is the URL authority: the host (bracketed if IPv6) plus ":port" if given
+func (t Target) hostPort() string {
+	if t.Port != "" {
+		return net.JoinHostPort(t.Host, t.Port)
+	}
+	if t.Kind == HostIPv6 {
+		return "[" + t.Host + "]"
+	}
+	return t.Host
+}
+
+// DialAddress is host:port to connect to, using the scheme's default port if none was given
+func (t Target) DialAddress() string {
+	port := t.Port
+	if port == "" {
+		port = defaultPorts[t.Scheme]
+	}
+	return net.JoinHostPort(t.Host, port)
+}
+
+// URL builds the request URL. The path keeps its original escaping.
+func (t Target) URL() *url.URL {
+	u := &url.URL{Scheme: t.Scheme, Host: t.hostPort(), RawQuery: t.Query}
+	if t.userinfo != "" {
+		name, password, hasPassword := strings.Cut(t.userinfo, ":")
+		name, _ = url.PathUnescape(name)
+		if hasPassword {
+			password, _ = url.PathUnescape(password)
+			u.User = url.UserPassword(name, password)
+		} else {
+			u.User = url.User(name)
+		}
+	}
+	u.Path, _ = url.PathUnescape(t.Path)
+	u.RawPath = t.Path
+	return u
+}
+
+// String is the target as a full URL
+func (t Target) String() string {
+	return t.URL().String()
+}
@@ -0,0 +1,601 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// builtinTemplateFS holds the templates shipped with the binary (-templates builtin)
+//
+//go:embed templates/*.yaml
+var builtinTemplateFS embed.FS
+
+// builtinTemplatesName is the -templates entry that selects the embedded templates
+const builtinTemplatesName = "builtin"
+
+// checkTemplate is a declarative check loaded from YAML. A template matches a target
+// when any of its requests matches; extractors run on the matching response.
+type checkTemplate struct {
+	ID          string            `yaml:"id"`
+	Name        string            `yaml:"name"`
+	Severity    string            `yaml:"severity"` // critical, high, medium, low or info
+	Description string            `yaml:"description"`
+	Requests    []templateRequest `yaml:"requests"`
+	source      string            // File the template was loaded from
+}
+
+// templateRequest is one HTTP request of a template and the conditions its response must meet.
+// Method, path, header values and body may use the same {{Placeholders}} as -raw.
+type templateRequest struct {
+	Method            string              `yaml:"method"`
+	Path              string              `yaml:"path"` // Relative to {{BaseURL}}; defaults to the target's own path
+	Headers           map[string]string   `yaml:"headers"`
+	Body              string              `yaml:"body"`
+	MatchersCondition string              `yaml:"matchers-condition"` // "and" or "or" (default)
+	Matchers          []templateMatcher   `yaml:"matchers"`
+	Extractors        []templateExtractor `yaml:"extractors"`
+}
+
+// templateMatcher is a single condition on a response
+type templateMatcher struct {
+	Type            string   `yaml:"type"`      // status, word, regex, header or size
+	Part            string   `yaml:"part"`      // body (default), header or all; for word and regex
+	Name            string   `yaml:"name"`      // Header name, for type header
+	Status          []int    `yaml:"status"`    // For type status
+	Words           []string `yaml:"words"`     // For word, and optionally header
+	Regex           []string `yaml:"regex"`     // For regex, and optionally header
+	Size            []int    `yaml:"size"`      // Exact body lengths, for type size
+	Condition       string   `yaml:"condition"` // How multiple words/regexes combine: "or" (default) or "and"
+	Negative        bool     `yaml:"negative"`  // Invert the result
+	CaseInsensitive bool     `yaml:"case-insensitive"`
+
+	compiled []*regexp.Regexp // Regex compiled at load time; nil entries use placeholders and are built per response
+}
+
+// templateExtractor captures data from a matching response
+type templateExtractor struct {
+	Name  string   `yaml:"name"`
+	Part  string   `yaml:"part"` // body (default), header or all
+	Regex []string `yaml:"regex"`
+	Group int      `yaml:"group"` // Capture group to keep (0 = whole match)
+
+	compiled []*regexp.Regexp // Regex, compiled at load time
+}
+
+// templateFinding is a template that matched a target
+type templateFinding struct {
+	templateID string
+	name       string
+	severity   string
+	url        string
+	status     int
+	extracted  []string // "name=value" pairs
+}
+
+// templateResult holds the template findings for one target
+type templateResult struct {
+	target   string
+	findings []templateFinding
+	errs     []error // Request failures; a failing template doesn't stop the others
+}
+
+// templateSeverities are the accepted severities, most severe first
+var templateSeverities = []string{"critical", "high", "medium", "low", "info"}
+
+// loadTemplates loads templates from a comma-separated list of directories, single
+// .yaml/.yml files and the word "builtin". Template IDs must be unique.
+func loadTemplates(spec string) ([]*checkTemplate, error) {
+	var templates []*checkTemplate
+	seen := make(map[string]string)
+	add := func(t *checkTemplate) error {
+		if prev, ok := seen[t.ID]; ok {
+			return fmt.Errorf("duplicate template id %q in %s and %s", t.ID, prev, t.source)
+		}
+		seen[t.ID] = t.source
+		templates = append(templates, t)
+		return nil
+	}
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if entry == builtinTemplatesName {
+			files, _ := fs.Glob(builtinTemplateFS, "templates/*.yaml")
+			for _, name := range files {
+				data, err := builtinTemplateFS.ReadFile(name)
+				if err != nil {
+					return nil, err
+				}
+				t, err := parseTemplate(data, "builtin:"+filepath.Base(name))
+				if err != nil {
+					return nil, err
+				}
+				if err := add(t); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+
+		info, err := os.Stat(entry)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read templates from %s: %w", entry, err)
+		}
+		files := []string{entry}
+		if info.IsDir() {
+			files = nil
+			err := filepath.WalkDir(entry, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if ext := filepath.Ext(path); !d.IsDir() && (ext == ".yaml" || ext == ".yml") {
+					files = append(files, path)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to read templates from %s: %w", entry, err)
+			}
+		}
+		for _, path := range files {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read template %s: %w", path, err)
+			}
+			t, err := parseTemplate(data, path)
+			if err != nil {
+				return nil, err
+			}
+			if err := add(t); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return templates, nil
+}
+
+// parseTemplate decodes and validates a template. Unknown keys are rejected so a
+// typo in a matcher doesn't silently turn it into one that always matches.
+func parseTemplate(data []byte, source string) (*checkTemplate, error) {
+	var t checkTemplate
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&t); err != nil {
+		return nil, fmt.Errorf("invalid template %s: %w", source, err)
+	}
+	t.source = source
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("invalid template %s: %s", source, fmt.Sprintf(format, args...))
+	}
+
+	if t.ID == "" {
+		return nil, invalid("missing id")
+	}
+	if t.Name == "" {
+		t.Name = t.ID
+	}
+	t.Severity = strings.ToLower(t.Severity)
+	if t.Severity == "" {
+		t.Severity = "info"
+	}
+	if severityRank(t.Severity) < 0 {
+		return nil, invalid("unknown severity %q", t.Severity)
+	}
+	if len(t.Requests) == 0 {
+		return nil, invalid("no requests")
+	}
+
+	for i := range t.Requests {
+		req := &t.Requests[i]
+		req.Method = strings.ToUpper(req.Method)
+		if req.Method == "" {
+			req.Method = "GET"
+		}
+		if !validCondition(req.MatchersCondition) {
+			return nil, invalid("request %d: matchers-condition must be 'and' or 'or'", i+1)
+		}
+		if len(req.Matchers) == 0 {
+			return nil, invalid("request %d has no matchers", i+1)
+		}
+		for j := range req.Matchers {
+			if err := req.Matchers[j].prepare(); err != nil {
+				return nil, invalid("request %d, matcher %d: %v", i+1, j+1, err)
+			}
+		}
+		for j := range req.Extractors {
+			e := &req.Extractors[j]
+			if e.Name == "" || len(e.Regex) == 0 || !validPart(e.Part) {
+				return nil, invalid("request %d, extractor %d: needs a name, a regex and a valid part", i+1, j+1)
+			}
+			for _, expr := range e.Regex {
+				re, err := regexp.Compile(expr)
+				if err != nil {
+					return nil, invalid("request %d, extractor %d: %v", i+1, j+1, err)
+				}
+				if e.Group > re.NumSubexp() {
+					return nil, invalid("request %d, extractor %d: group %d out of range", i+1, j+1, e.Group)
+				}
+				e.compiled = append(e.compiled, re)
+			}
+		}
+	}
+	return &t, nil
+}
+
+// prepare checks that a matcher has what its type needs and compiles its regexes
+func (m *templateMatcher) prepare() error {
+	if !validCondition(m.Condition) {
+		return fmt.Errorf("condition must be 'and' or 'or'")
+	}
+	if !validPart(m.Part) {
+		return fmt.Errorf("part must be body, header or all")
+	}
+	switch m.Type {
+	case "status":
+		if len(m.Status) == 0 {
+			return fmt.Errorf("status matcher needs a status list")
+		}
+	case "word":
+		if len(m.Words) == 0 {
+			return fmt.Errorf("word matcher needs words")
+		}
+	case "regex":
+		if len(m.Regex) == 0 {
+			return fmt.Errorf("regex matcher needs regex")
+		}
+	case "header":
+		if m.Name == "" {
+			return fmt.Errorf("header matcher needs a header name")
+		}
+	case "size":
+		if len(m.Size) == 0 {
+			return fmt.Errorf("size matcher needs a size list")
+		}
+	default:
+		return fmt.Errorf("unknown matcher type %q", m.Type)
+	}
+	// Placeholders are rendered (and regex-quoted) per target, so those regexes are only
+	// validated here, with sample values; the rest are compiled once and kept
+	m.compiled = make([]*regexp.Regexp, len(m.Regex))
+	for i, expr := range m.Regex {
+		if m.CaseInsensitive {
+			expr = "(?i)" + expr
+		}
+		if rawPlaceholder.MatchString(expr) { // From rawrequest.go
+			if _, err := regexp.Compile(renderPlaceholders(expr, sampleRegexValues)); err != nil {
+				return err
+			}
+			continue
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return err
+		}
+		m.compiled[i] = re
+	}
+	return nil
+}
+
+// sampleRegexValues stand in for placeholders when validating regexes at load time
+var sampleRegexValues = map[string]string{
+	"Host": "example\\.com", "Hostname": "example\\.com", "Port": "80", "Path": "/",
+	"Scheme": "http", "BaseURL": "http://example\\.com", "RandStr": "abc", "RandInt": "1",
+}
+
+func validCondition(c string) bool { return c == "" || c == "and" || c == "or" }
+
+func validPart(p string) bool { return p == "" || p == "body" || p == "header" || p == "all" }
+
+// severityRank orders severities, critical first (-1 for unknown ones)
+func severityRank(severity string) int {
+	for i, s := range templateSeverities {
+		if s == severity {
+			return i
+		}
+	}
+	return -1
+}
+
+// runTemplates executes every template against a live target
+func runTemplates(target string, mainResp *probeResponse, client *http.Client, reqCfg *requestConfig, templates []*checkTemplate) templateResult {
+	result := templateResult{target: target}
+	for _, t := range templates {
+		for _, tr := range t.Requests {
+			finding, err := runTemplateRequest(t, tr, mainResp, client, reqCfg)
+			if err != nil {
+				result.errs = append(result.errs, fmt.Errorf("template %s: %w", t.ID, err))
+				continue
+			}
+			if finding != nil {
+				result.findings = append(result.findings, *finding)
+				break // One finding per template is enough
+			}
+		}
+	}
+	return result
+}
+
+// templateResponse is what matchers and extractors look at
+type templateResponse struct {
+	status int
+	header string // "Name: value" lines, sorted by name
+	body   string
+	values map[string]string // Placeholder values used for the request, for matchers that echo them
+	hdr    http.Header
+}
+
+// part returns the text a word/regex matcher or extractor inspects
+func (r *templateResponse) part(name string) string {
+	switch name {
+	case "header":
+		return r.header
+	case "all":
+		return r.header + "\n" + r.body
+	default:
+		return r.body
+	}
+}
+
+// runTemplateRequest sends one template request and evaluates its matchers.
+// It returns nil (and no error) when the response didn't match.
+func runTemplateRequest(t *checkTemplate, tr templateRequest, mainResp *probeResponse, client *http.Client, reqCfg *requestConfig) (*templateFinding, error) {
+	values := placeholderValues(mainResp.url) // From rawrequest.go
+	reqURL := mainResp.url.String()
+	if tr.Path != "" {
+		path := renderPlaceholders(tr.Path, values)
+		if !strings.HasPrefix(path, "http://") && !strings.HasPrefix(path, "https://") {
+			if !strings.HasPrefix(path, "/") {
+				path = "/" + path
+			}
+			path = values["BaseURL"] + path
+		}
+		reqURL = path
+	}
+
+	var body io.Reader
+	if tr.Body != "" {
+		body = strings.NewReader(renderPlaceholders(tr.Body, values))
+	}
+	req, err := http.NewRequest(renderPlaceholders(tr.Method, values), reqURL, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request for %s: %w", reqURL, err)
+	}
+	// -H/-cookie/-ua first, so the template's own headers win
+	reqCfg.apply(req, "HyperScanner/1.4+Templates")
+	for name, value := range tr.Headers {
+		if strings.EqualFold(name, "Host") {
+			req.Host = renderPlaceholders(value, values)
+			continue
+		}
+		req.Header.Set(name, renderPlaceholders(value, values))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	resp.Body.Close()
+
+	tresp := &templateResponse{status: resp.StatusCode, body: string(respBody), values: values, hdr: resp.Header}
+	names := make([]string, 0, len(resp.Header))
+	for name := range resp.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var hb strings.Builder
+	for _, name := range names {
+		for _, v := range resp.Header[name] {
+			hb.WriteString(name + ": " + v + "\n")
+		}
+	}
+	tresp.header = hb.String()
+
+	if !matchAll(tr.Matchers, tr.MatchersCondition == "and", tresp) {
+		return nil, nil
+	}
+
+	finding := &templateFinding{
+		templateID: t.ID,
+		name:       t.Name,
+		severity:   t.Severity,
+		url:        reqURL,
+		status:     resp.StatusCode,
+	}
+	for _, e := range tr.Extractors {
+		for _, value := range e.extract(tresp) {
+			finding.extracted = append(finding.extracted, e.Name+"="+value)
+		}
+	}
+	return finding, nil
+}
+
+// matchAll combines matcher results with AND or OR
+func matchAll(matchers []templateMatcher, and bool, r *templateResponse) bool {
+	for _, m := range matchers {
+		ok := m.match(r)
+		if and && !ok {
+			return false
+		}
+		if !and && ok {
+			return true
+		}
+	}
+	return and
+}
+
+// match evaluates one matcher, honouring negative
+func (m templateMatcher) match(r *templateResponse) bool {
+	var ok bool
+	and := m.Condition == "and"
+	switch m.Type {
+	case "status":
+		ok = containsInt(m.Status, r.status)
+	case "size":
+		ok = containsInt(m.Size, len(r.body))
+	case "word":
+		ok = m.matchWords(r.part(m.Part), r.values)
+	case "regex":
+		ok = m.matchRegexes(r.part(m.Part), r.values)
+	case "header":
+		values := r.hdr.Values(m.Name)
+		if len(values) == 0 {
+			ok = false
+			break
+		}
+		text := strings.Join(values, "\n")
+		switch {
+		case len(m.Words) > 0 && len(m.Regex) > 0:
+			w, re := m.matchWords(text, r.values), m.matchRegexes(text, r.values)
+			ok = (and && w && re) || (!and && (w || re))
+		case len(m.Words) > 0:
+			ok = m.matchWords(text, r.values)
+		case len(m.Regex) > 0:
+			ok = m.matchRegexes(text, r.values)
+		default:
+			ok = true // Header present
+		}
+	}
+	if m.Negative {
+		return !ok
+	}
+	return ok
+}
+
+// matchWords checks substrings, combined by the matcher's condition
+func (m templateMatcher) matchWords(text string, values map[string]string) bool {
+	and := m.Condition == "and"
+	if m.CaseInsensitive {
+		text = strings.ToLower(text)
+	}
+	for _, word := range m.Words {
+		word = renderPlaceholders(word, values)
+		if m.CaseInsensitive {
+			word = strings.ToLower(word)
+		}
+		found := strings.Contains(text, word)
+		if and && !found {
+			return false
+		}
+		if !and && found {
+			return true
+		}
+	}
+	return and
+}
+
+// matchRegexes checks regular expressions, combined by the matcher's condition.
+// Regexes with placeholders are built per response, with the values regex-quoted.
+func (m templateMatcher) matchRegexes(text string, values map[string]string) bool {
+	and := m.Condition == "and"
+	var quoted map[string]string
+	for i, re := range m.compiled {
+		if re == nil {
+			if quoted == nil {
+				quoted = make(map[string]string, len(values))
+				for k, v := range values {
+					quoted[k] = regexp.QuoteMeta(v)
+				}
+			}
+			expr := renderPlaceholders(m.Regex[i], quoted)
+			if m.CaseInsensitive {
+				expr = "(?i)" + expr
+			}
+			re, _ = regexp.Compile(expr)
+		}
+		found := re != nil && re.MatchString(text)
+		if and && !found {
+			return false
+		}
+		if !and && found {
+			return true
+		}
+	}
+	return and
+}
+
+// extract returns the unique captures of an extractor
+func (e templateExtractor) extract(r *templateResponse) []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, re := range e.compiled {
+		for _, m := range re.FindAllStringSubmatch(r.part(e.Part), -1) {
+			if v := m[e.Group]; v != "" && !seen[v] {
+				seen[v] = true
+				out = append(out, v)
+			}
+		}
+	}
+	return out
+}
+
+func containsInt(list []int, v int) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// describeFinding formats a finding for template_findings.txt and the console
+func describeFinding(f templateFinding) string {
+	s := fmt.Sprintf("[%s] [%s] %s (%s, status %d)", strings.ToUpper(f.severity), f.templateID, f.url, f.name, f.status)
+	if len(f.extracted) > 0 {
+		s += " extracted: " + strings.Join(f.extracted, ", ")
+	}
+	return s
+}
+
+// --- Template statistics (for the summary) ---
+var templateMatchCounts = make(map[string]int)
+var templateSeverityByID = make(map[string]string)
+var templateCountsMutex sync.Mutex
+
+// recordTemplateFindings adds a target's findings to the summary
+func recordTemplateFindings(findings []templateFinding) {
+	templateCountsMutex.Lock()
+	defer templateCountsMutex.Unlock()
+	for _, f := range findings {
+		templateMatchCounts[f.templateID]++
+		templateSeverityByID[f.templateID] = f.severity
+	}
+}
+
+// printTemplateSummary lists how many targets each template matched, most severe first
+func printTemplateSummary() {
+	templateCountsMutex.Lock()
+	defer templateCountsMutex.Unlock()
+	if len(templateMatchCounts) == 0 {
+		return
+	}
+	ids := make([]string, 0, len(templateMatchCounts))
+	for id := range templateMatchCounts {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		ri, rj := severityRank(templateSeverityByID[ids[i]]), severityRank(templateSeverityByID[ids[j]])
+		if ri != rj {
+			return ri < rj
+		}
+		return ids[i] < ids[j]
+	})
+	fmt.Println("\nTemplate Findings:")
+	for _, id := range ids {
+		fmt.Printf("  %s%-8s%s %-30s : %d\n", ColorFinding, strings.ToUpper(templateSeverityByID[id]), ColorReset, id, templateMatchCounts[id])
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http/httptrace"
+	"sort"
+	"sync"
+	"time"
+)
+
+// probeTiming is the per-phase latency of one request. Phases that didn't happen
+// (DNS for IP targets, connect/TLS on a reused connection, TLS over plain HTTP) stay zero.
+type probeTiming struct {
+	dns     time.Duration
+	connect time.Duration
+	tls     time.Duration
+	ttfb    time.Duration // Request start to first response byte
+	total   time.Duration // Request start to body fully read
+	reused  bool          // Connection came from the idle pool
+}
+
+// timingRecorder collects httptrace callbacks for a single request. Callbacks may
+// run on other goroutines (e.g. parallel dials), hence the mutex.
+type timingRecorder struct {
+	mu                     sync.Mutex
+	start                  time.Time
+	dnsStart, connectStart time.Time
+	tlsStart               time.Time
+	timing                 probeTiming
+	remoteAddr             string
+}
+
+// newTimingRecorder starts the clock and returns the trace hooks feeding it
+func newTimingRecorder() (*timingRecorder, *httptrace.ClientTrace) {
+	r := &timingRecorder{start: time.Now()}
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			r.mu.Lock()
+			r.dnsStart = time.Now()
+			r.mu.Unlock()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			r.mu.Lock()
+			r.timing.dns = time.Since(r.dnsStart)
+			r.mu.Unlock()
+		},
+		ConnectStart: func(string, string) {
+			r.mu.Lock()
+			if r.connectStart.IsZero() {
+				r.connectStart = time.Now()
+			}
+			r.mu.Unlock()
+		},
+		ConnectDone: func(_, _ string, err error) {
+			r.mu.Lock()
+			if err == nil && r.timing.connect == 0 {
+				r.timing.connect = time.Since(r.connectStart)
+			}
+			r.mu.Unlock()
+		},
+		TLSHandshakeStart: func() {
+			r.mu.Lock()
+			r.tlsStart = time.Now()
+			r.mu.Unlock()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			r.mu.Lock()
+			r.timing.tls = time.Since(r.tlsStart)
+			r.mu.Unlock()
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			r.mu.Lock()
+			r.timing.reused = info.Reused
+			r.remoteAddr = info.Conn.RemoteAddr().String()
+			r.mu.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			r.mu.Lock()
+			r.timing.ttfb = time.Since(r.start)
+			r.mu.Unlock()
+		},
+	}
+	return r, trace
+}
+
+// finish stops the clock and returns the collected timing and the remote address
+func (r *timingRecorder) finish() (*probeTiming, string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t := r.timing
+	t.total = time.Since(r.start)
+	return &t, r.remoteAddr
+}
+
+// describe formats the timing for logs and slow_hosts.txt
+func (t *probeTiming) describe() string {
+	ms := func(d time.Duration) string { return d.Round(time.Millisecond).String() }
+	s := fmt.Sprintf("total=%s dns=%s connect=%s tls=%s ttfb=%s", ms(t.total), ms(t.dns), ms(t.connect), ms(t.tls), ms(t.ttfb))
+	if t.reused {
+		s += " (reused conn)"
+	}
+	return s
+}
+
+// --- Latency statistics (for the summary) ---
+var timingSamples = map[string][]time.Duration{}
+var slowHostCount int64
+var slowHostThreshold time.Duration // Threshold the slow count refers to (0 = not tracked)
+var timingSamplesMutex sync.Mutex
+
+// timingPhases fixes the order phases are printed in
+var timingPhases = []string{"DNS", "Connect", "TLS", "TTFB", "Total"}
+
+// recordTiming adds a successful probe's timing to the summary statistics and reports
+// whether it exceeded the slow-host threshold (0 disables the check).
+// Phases that didn't happen are left out so they don't drag percentiles to zero.
+func recordTiming(t *probeTiming, threshold time.Duration) bool {
+	timingSamplesMutex.Lock()
+	defer timingSamplesMutex.Unlock()
+	add := func(phase string, d time.Duration, always bool) {
+		if d > 0 || always {
+			timingSamples[phase] = append(timingSamples[phase], d)
+		}
+	}
+	add("DNS", t.dns, false)
+	add("Connect", t.connect, false)
+	add("TLS", t.tls, false)
+	add("TTFB", t.ttfb, true)
+	add("Total", t.total, true)
+
+	slowHostThreshold = threshold
+	slow := threshold > 0 && t.total > threshold
+	if slow {
+		slowHostCount++
+	}
+	return slow
+}
+
+// percentile returns the nearest-rank percentile of already sorted samples
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(p/100*float64(len(sorted))+0.5) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
+
+// timingStat is one phase's row of the latency summary
+type timingStat struct {
+	Phase         string
+	P50, P90, P99 time.Duration
+	Samples       int
+}
+
+// timingStats computes p50/p90/p99 for every phase that has samples, in timingPhases order
+func timingStats() []timingStat {
+	timingSamplesMutex.Lock()
+	defer timingSamplesMutex.Unlock()
+	var stats []timingStat
+	for _, phase := range timingPhases {
+		samples := timingSamples[phase]
+		if len(samples) == 0 {
+			continue
+		}
+		sorted := append([]time.Duration(nil), samples...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		stats = append(stats, timingStat{
+			Phase:   phase,
+			P50:     percentile(sorted, 50).Round(time.Millisecond),
+			P90:     percentile(sorted, 90).Round(time.Millisecond),
+			P99:     percentile(sorted, 99).Round(time.Millisecond),
+			Samples: len(sorted),
+		})
+	}
+	return stats
+}
+
+// printTimingSummary prints p50/p90/p99 per phase and the number of slow hosts
+func printTimingSummary() {
+	stats := timingStats()
+	if len(stats) == 0 {
+		return
+	}
+
+	fmt.Println("\nLatency Percentiles:")
+	fmt.Printf("  %-8s %10s %10s %10s %8s\n", "Phase", "p50", "p90", "p99", "Samples")
+	for _, s := range stats {
+		fmt.Printf("  %-8s %10s %10s %10s %8d\n", s.Phase, s.P50, s.P90, s.P99, s.Samples)
+	}
+	timingSamplesMutex.Lock()
+	defer timingSamplesMutex.Unlock()
+	if slowHostThreshold > 0 {
+		fmt.Printf("  %sSlow hosts (> %s): %d%s\n", ColorWarning, slowHostThreshold, slowHostCount, ColorReset)
+	}
+}
@@ -23,7 +23,7 @@ func printBanner() {
 ██║  ██║██╔╝ ██╗      ███████║╚██████╗██║  ██║██║ ╚████║██║ ╚████║███████╗██║  ██║
 ╚═╝  ╚═╝╚═╝  ╚═╝      ╚══════╝ ╚═════╝╚═╝  ╚═╝╚═╝  ╚═══╝╚═╝  ╚═══╝╚══════╝╚═╝  ╚═╝
 	`)
-	fmt.Println(ColorAccent + "         HyperScanner v1.4+CORS (IP/Domain/URL Scanner)" + ColorReset)
+	fmt.Println(ColorAccent + "         HyperScanner v" + version + "+CORS (IP/Domain/URL Scanner)" + ColorReset)
 	fmt.Println()
 }
 
@@ -120,7 +120,7 @@ func printSummary(
 	finalFailed := atomic.LoadInt64(failedScans)
 
 	fmt.Printf("\n--- %s Summary (%s) ---\n", title, time.Since(startTime).Round(time.Millisecond))
-	fmt.Printf("Total Targets: %d\n", totalTargets)
+	fmt.Printf("Total Targets: %d%s\n", totalTargets, describeDiscovered())
 	fmt.Printf("%sSuccessful Scans: %d%s\n", ColorSuccess, finalSuccess, ColorReset)
 	fmt.Printf("%sFailed Scans:     %d%s\n", ColorError, finalFailed, ColorReset)
 
@@ -128,8 +128,26 @@ func printSummary(
 		// Use the helper function from main.go
 		printStatusBreakdown(statusCounts, statusCountsMutex)
 	}
+	printSectionSummaries()
 
 	fmt.Printf("\n%s[*]%s Output saved to: %s%s%s\n", ColorInfo, ColorReset, ColorAccent, outputDir, ColorReset)
 }
 
+// printSectionSummaries prints the per-feature sections that follow the status code
+// table. Shared by printSummary and the final summary main.go prints without a re-scan.
+func printSectionSummaries() {
+	printDedupSummary()
+	printInputSummary()     // No-op unless several -i sources were merged
+	printFilterSummary()    // No-op unless -match-*/-filter-* removed results
+	printDiscoverySummary() // No-op unless targets were discovered during the scan
+	printScopeSummary()     // No-op unless -scope stopped something
+	printHarvestSummary()   // No-op unless -harvest ran
+	printErrorBreakdown()   // Failures per error class, next to the status code table
+	printTimingSummary()    // Latency percentiles per phase
+	printWAFSummary()       // No-op unless -waf detected something
+	printTemplateSummary()  // No-op unless -templates matched something
+	printExtractSummary()   // No-op unless -extract found something
+	printFaviconSummary()   // No-op unless -favicon recorded hashes
+}
+
 // Note: printStatusBreakdown function is now in main.go
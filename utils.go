@@ -2,6 +2,8 @@ package main
 
 import (
 	"bufio"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"strings"
@@ -49,3 +51,13 @@ func readTargetsFromFile(filePath string) ([]string, error) {
 	}
 	return targets, nil
 }
+
+// randomToken returns n random bytes hex-encoded, used for canaries and cache busters
+func randomToken(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		// crypto/rand failing is exceptional; fall back to something still unique enough
+		return fmt.Sprintf("%x", os.Getpid())
+	}
+	return hex.EncodeToString(b)
+}
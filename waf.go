@@ -0,0 +1,470 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// wafResult holds the WAF/CDN fingerprinting outcome for a live target
+type wafResult struct {
+	target      string
+	waf         string        // Detected WAF product ("" if none)
+	cdn         string        // Detected CDN/edge network ("" if none)
+	blocked     bool          // The primary response itself looks like a WAF block page
+	evidence    []string      // Human readable reasons, e.g. "header cf-ray"
+	probed      bool          // An active probe request was sent
+	probeStatus int           // Status code of the probe request (0 if not sent or failed)
+	throttled   time.Duration // Backoff started for the host because the response signalled throttling (0 = none)
+	err         error         // Probe error only; passive detection cannot fail
+}
+
+// wafSignature describes how to recognise one WAF or CDN product.
+// A product may be both (e.g. Cloudflare), in which case both flags are set.
+type wafSignature struct {
+	name      string
+	isWAF     bool
+	isCDN     bool
+	headers   []string         // Header names whose presence identifies the product
+	server    *regexp.Regexp   // Matched against the Server (and Via) header values
+	cookies   []string         // Cookie name prefixes set by the product
+	blockBody []*regexp.Regexp // Block page signatures; a match means the request was blocked
+}
+
+// wafSignatures is the built-in fingerprint database, checked in order
+var wafSignatures = []wafSignature{
+	{
+		name: "Cloudflare", isWAF: true, isCDN: true,
+		headers: []string{"cf-ray", "cf-cache-status", "cf-mitigated"},
+		server:  regexp.MustCompile(`(?i)cloudflare`),
+		cookies: []string{"__cf_bm", "__cfduid", "cf_clearance", "__cflb"},
+		blockBody: []*regexp.Regexp{
+			regexp.MustCompile(`(?i)Attention Required! \| Cloudflare`),
+			regexp.MustCompile(`(?i)Sorry, you have been blocked`),
+			regexp.MustCompile(`(?i)cf-error-details`),
+		},
+	},
+	{
+		name: "Akamai", isWAF: true, isCDN: true,
+		headers: []string{"x-akamai-transformed", "x-akamai-request-id", "x-akamai-session-info", "akamai-grn", "x-akamai-edgescape"},
+		server:  regexp.MustCompile(`(?i)akamai(ghost|netstorage)?`),
+		cookies: []string{"ak_bmsc", "bm_sz", "_abck"},
+		blockBody: []*regexp.Regexp{
+			regexp.MustCompile(`(?is)Access Denied.*You don't have permission to access.*Reference #`),
+			regexp.MustCompile(`(?i)errors\.edgesuite\.net`),
+		},
+	},
+	{
+		name: "Sucuri", isWAF: true, isCDN: true,
+		headers: []string{"x-sucuri-id", "x-sucuri-cache", "x-sucuri-block"},
+		server:  regexp.MustCompile(`(?i)sucuri|cloudproxy`),
+		blockBody: []*regexp.Regexp{
+			regexp.MustCompile(`(?i)Sucuri WebSite Firewall - Access Denied`),
+			regexp.MustCompile(`(?i)cloudproxy@sucuri\.net`),
+		},
+	},
+	{
+		name: "Imperva Incapsula", isWAF: true, isCDN: true,
+		headers: []string{"x-iinfo"},
+		server:  regexp.MustCompile(`(?i)incapsula`),
+		cookies: []string{"incap_ses_", "visid_incap_", "nlbi_"},
+		blockBody: []*regexp.Regexp{
+			regexp.MustCompile(`(?i)Incapsula incident ID`),
+			regexp.MustCompile(`(?i)_Incapsula_Resource`),
+		},
+	},
+	{
+		name: "AWS CloudFront", isCDN: true,
+		headers: []string{"x-amz-cf-id", "x-amz-cf-pop"},
+		server:  regexp.MustCompile(`(?i)cloudfront`),
+		blockBody: []*regexp.Regexp{
+			regexp.MustCompile(`(?is)Generated by cloudfront \(CloudFront\).*Request blocked`),
+		},
+	},
+	{
+		name: "AWS WAF", isWAF: true,
+		headers: []string{"x-amzn-waf-action"},
+		cookies: []string{"aws-waf-token"},
+	},
+	{
+		name: "Fastly", isCDN: true,
+		headers: []string{"x-fastly-request-id", "fastly-debug-digest"},
+		server:  regexp.MustCompile(`(?i)fastly`),
+	},
+	{
+		name: "Azure Front Door", isWAF: true, isCDN: true,
+		headers: []string{"x-azure-ref", "x-fd-healthprobe"},
+		blockBody: []*regexp.Regexp{
+			regexp.MustCompile(`(?i)The request is blocked\.`),
+		},
+	},
+	{
+		name: "Google Cloud CDN", isCDN: true,
+		server: regexp.MustCompile(`(?i)^1\.1 google$`),
+	},
+	{
+		name: "F5 BIG-IP ASM", isWAF: true,
+		cookies: []string{"TS01", "BIGipServer", "F5_ST", "MRHSession"},
+		server:  regexp.MustCompile(`(?i)big-?ip`),
+		blockBody: []*regexp.Regexp{
+			regexp.MustCompile(`(?i)The requested URL was rejected\. Please consult with your administrator`),
+			regexp.MustCompile(`(?i)Your support ID is`),
+		},
+	},
+	{
+		name: "ModSecurity", isWAF: true,
+		server: regexp.MustCompile(`(?i)mod_security|modsecurity|NOYB`),
+		blockBody: []*regexp.Regexp{
+			regexp.MustCompile(`(?i)This error was generated by Mod_Security`),
+			regexp.MustCompile(`(?i)ModSecurity Action`),
+		},
+	},
+	{
+		name: "Barracuda", isWAF: true,
+		cookies: []string{"barra_counter_session", "BNI__BARRACUDA_LB_COOKIE", "BNI_persistence"},
+		blockBody: []*regexp.Regexp{
+			regexp.MustCompile(`(?i)You have been blocked.*Barracuda`),
+		},
+	},
+	{
+		name: "FortiWeb", isWAF: true,
+		cookies: []string{"FORTIWAFSID", "cookiesession1"},
+		blockBody: []*regexp.Regexp{
+			regexp.MustCompile(`(?i)\.fgd_icon|FortiGuard Intrusion Prevention`),
+		},
+	},
+	{
+		name: "Wordfence", isWAF: true,
+		blockBody: []*regexp.Regexp{
+			regexp.MustCompile(`(?i)Generated by Wordfence`),
+			regexp.MustCompile(`(?i)Your access to this site has been limited`),
+		},
+	},
+	{
+		name: "StackPath", isWAF: true, isCDN: true,
+		headers: []string{"x-sp-waf", "x-sp-url"},
+		server:  regexp.MustCompile(`(?i)stackpath`),
+	},
+	{
+		name: "KeyCDN", isCDN: true,
+		server: regexp.MustCompile(`(?i)keycdn-engine`),
+	},
+}
+
+// wafProbeQuery is appended to the target URL for the active probe. It looks like an
+// attack to a WAF (XSS, traversal, SQLi) but contains nothing that could cause harm.
+const wafProbeQuery = "hxwaf=%3Cscript%3Ealert(1)%3C%2Fscript%3E&file=..%2F..%2F..%2F..%2Fetc%2Fpasswd&id=1%27%20OR%20%271%27%3D%271"
+
+// Status codes WAFs typically answer a blocked request with (besides any 5xx)
+var wafBlockStatuses = map[int]bool{403: true, 406: true, 419: true, 429: true, 501: true, 999: true}
+
+// --- WAF/CDN counts (for the summary) ---
+var wafCounts = make(map[string]int)
+var wafBlockedCount, wafThrottledCount int
+var wafCountsMutex sync.Mutex
+
+// checkWAF fingerprints the primary response and, if probe is set, sends one
+// suspicious-looking request and compares it to the primary response.
+func checkWAF(target string, mainResp *probeResponse, client *http.Client, probe bool) wafResult {
+	result := wafResult{target: target}
+
+	wafSig, cdnSig, evidence := matchWAFSignatures(mainResp.header)
+	result.evidence = evidence
+	if wafSig != nil {
+		result.waf = wafSig.name
+	}
+	if cdnSig != nil {
+		result.cdn = cdnSig.name
+	}
+	if blocker := matchBlockPage(mainResp.statusCode, mainResp.body); blocker != nil {
+		result.blocked = true
+		result.waf = blocker.name
+		result.evidence = append(result.evidence, "block page ("+blocker.name+")")
+	}
+
+	if !probe {
+		return result
+	}
+
+	// --- Active probe ---
+	result.probed = true
+	probeURL := *mainResp.url
+	if probeURL.RawQuery != "" {
+		probeURL.RawQuery += "&" + wafProbeQuery
+	} else {
+		probeURL.RawQuery = wafProbeQuery
+	}
+	req, err := http.NewRequest("GET", probeURL.String(), nil)
+	if err != nil {
+		result.err = fmt.Errorf("failed to create WAF probe request for %s: %w", probeURL.String(), err)
+		return result
+	}
+	req.Header.Set("User-Agent", "HyperScanner/1.4+WAFProbe")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		// Some WAFs reset the connection instead of answering; worth noting but not conclusive
+		result.err = fmt.Errorf("WAF probe request failed for %s: %w", probeURL.Redacted(), err)
+		return result
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	resp.Body.Close()
+	result.probeStatus = resp.StatusCode
+
+	if blocker := matchBlockPage(resp.StatusCode, body); blocker != nil {
+		result.waf = blocker.name
+		result.evidence = append(result.evidence, fmt.Sprintf("probe blocked with %d (%s)", resp.StatusCode, blocker.name))
+		return result
+	}
+	if resp.StatusCode != mainResp.statusCode && wafBlockStatuses[resp.StatusCode] {
+		if probeSig, _, _ := matchWAFSignatures(resp.Header); probeSig != nil && result.waf == "" {
+			result.waf = probeSig.name
+		}
+		if result.waf == "" {
+			result.waf = "Generic WAF"
+		}
+		result.evidence = append(result.evidence, fmt.Sprintf("probe status %d vs baseline %d", resp.StatusCode, mainResp.statusCode))
+	}
+	return result
+}
+
+// matchWAFSignatures picks the WAF and the CDN from the products whose headers, server
+// value or cookies match. They are chosen independently, so a CDN in front of a separate
+// WAF (e.g. Akamai in front of Imperva) reports both; a product that is both (Cloudflare
+// on its own) fills both fields. The evidence covers the chosen products.
+func matchWAFSignatures(header http.Header) (waf, cdn *wafSignature, evidence []string) {
+	var matched []*wafSignature
+	evidenceOf := make(map[*wafSignature][]string)
+	serverValues := header.Get("Server") + "\n" + strings.Join(header.Values("Via"), "\n") + "\n" + header.Get("X-CDN")
+	cookieNames := responseCookieNames(header)
+
+	for i := range wafSignatures {
+		sig := &wafSignatures[i]
+		var evidence []string
+		for _, h := range sig.headers {
+			if header.Get(h) != "" {
+				evidence = append(evidence, "header "+h)
+			}
+		}
+		if sig.server != nil {
+			for _, v := range strings.Split(serverValues, "\n") {
+				if v != "" && sig.server.MatchString(v) {
+					evidence = append(evidence, "server/via '"+v+"'")
+					break
+				}
+			}
+		}
+		for _, prefix := range sig.cookies {
+			for _, name := range cookieNames {
+				if strings.HasPrefix(name, prefix) {
+					evidence = append(evidence, "cookie "+name)
+				}
+			}
+		}
+		if len(evidence) > 0 {
+			matched = append(matched, sig)
+			evidenceOf[sig] = evidence
+		}
+	}
+
+	for _, sig := range matched {
+		if sig.isCDN {
+			cdn = sig
+			break
+		}
+	}
+	for _, sig := range matched {
+		if sig.isWAF && sig != cdn {
+			waf = sig // A dedicated WAF behind the CDN
+			break
+		}
+	}
+	if waf == nil && cdn != nil && cdn.isWAF {
+		waf = cdn
+	}
+	for _, sig := range matched {
+		if sig == waf || sig == cdn {
+			evidence = append(evidence, evidenceOf[sig]...)
+		}
+	}
+	return waf, cdn, evidence
+}
+
+// matchBlockPage returns the product whose block page signature matches the body.
+// Only error responses are considered: a normal 200 page that happens to mention
+// "Access Denied" is not a block page.
+func matchBlockPage(statusCode int, body []byte) *wafSignature {
+	if len(body) == 0 || !(wafBlockStatuses[statusCode] || statusCode >= 500) {
+		return nil
+	}
+	for i := range wafSignatures {
+		for _, re := range wafSignatures[i].blockBody {
+			if re.Match(body) {
+				return &wafSignatures[i]
+			}
+		}
+	}
+	return nil
+}
+
+// --- Per-host backoff when a WAF throttles us ---
+
+const (
+	wafBackoffBase = 5 * time.Second  // First backoff when no Retry-After is given; doubles on repeats
+	wafBackoffMax  = 60 * time.Second // Cap, also applied to Retry-After
+)
+
+// wafBackoff tracks, per host:port, when requests may resume and how often it throttled
+type wafBackoff struct {
+	until   time.Time
+	strikes int
+}
+
+var wafBackoffs = make(map[string]*wafBackoff)
+var wafBackoffMutex sync.Mutex
+
+// wafThrottleDelay reports how long to back off after a response: a 429 always counts,
+// as does a Retry-After on a WAF block status or a 503 from a host behind a WAF.
+// Returns 0 when the response doesn't signal throttling.
+func wafThrottleDelay(resp *probeResponse, res *wafResult, strikes int) time.Duration {
+	retryAfter := resp.header.Get("Retry-After")
+	behindWAF := res.waf != "" || res.blocked
+	if resp.statusCode != http.StatusTooManyRequests &&
+		!(retryAfter != "" && behindWAF && (wafBlockStatuses[resp.statusCode] || resp.statusCode == http.StatusServiceUnavailable)) {
+		return 0
+	}
+	delay := wafBackoffBase << strikes
+	if secs, err := strconv.Atoi(strings.TrimSpace(retryAfter)); err == nil && secs > 0 {
+		delay = time.Duration(secs) * time.Second
+	} else if at, err := http.ParseTime(retryAfter); err == nil && time.Until(at) > 0 {
+		delay = time.Until(at)
+	}
+	if delay <= 0 || delay > wafBackoffMax {
+		delay = wafBackoffMax
+	}
+	return delay
+}
+
+// startWAFBackoff records that a host throttled a response and returns the delay it
+// was given (0 if the response wasn't a throttling signal)
+func startWAFBackoff(hostKey string, resp *probeResponse, res *wafResult) time.Duration {
+	wafBackoffMutex.Lock()
+	defer wafBackoffMutex.Unlock()
+	b := wafBackoffs[hostKey]
+	if b == nil {
+		b = &wafBackoff{}
+		wafBackoffs[hostKey] = b
+	}
+	delay := wafThrottleDelay(resp, res, b.strikes)
+	if delay == 0 {
+		return 0
+	}
+	b.strikes++
+	if until := time.Now().Add(delay); until.After(b.until) {
+		b.until = until
+	}
+	return delay
+}
+
+// waitForWAFBackoff sleeps until a throttled host may be contacted again.
+// Workers that picked up other targets on the same host wait too.
+func waitForWAFBackoff(hostKey string) {
+	wafBackoffMutex.Lock()
+	var wait time.Duration
+	if b := wafBackoffs[hostKey]; b != nil {
+		wait = time.Until(b.until)
+	}
+	wafBackoffMutex.Unlock()
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+}
+
+// wafHostKey is the host:port backoff is tracked per ("" for unparseable targets)
+func wafHostKey(target string) string {
+	parsed, err := ParseTarget(target) // From target.go
+	if err != nil {
+		return ""
+	}
+	return parsed.DialAddress()
+}
+
+// responseCookieNames lists cookie names from Set-Cookie headers without fully parsing them
+func responseCookieNames(header http.Header) []string {
+	var names []string
+	for _, c := range header.Values("Set-Cookie") {
+		name, _, _ := strings.Cut(c, "=")
+		names = append(names, strings.TrimSpace(name))
+	}
+	return names
+}
+
+// recordWAF counts detected products, block pages and throttling for the summary
+func recordWAF(res *wafResult) {
+	wafCountsMutex.Lock()
+	defer wafCountsMutex.Unlock()
+	if res.blocked {
+		wafBlockedCount++
+	}
+	if res.throttled > 0 {
+		wafThrottledCount++
+	}
+	if res.waf != "" {
+		wafCounts["WAF: "+res.waf]++
+	}
+	if res.cdn != "" {
+		wafCounts["CDN: "+res.cdn]++
+	}
+}
+
+// printWAFSummary lists how many targets sit behind each detected WAF/CDN
+func printWAFSummary() {
+	wafCountsMutex.Lock()
+	defer wafCountsMutex.Unlock()
+	if len(wafCounts) == 0 && wafBlockedCount == 0 && wafThrottledCount == 0 {
+		return
+	}
+	names := make([]string, 0, len(wafCounts))
+	for name := range wafCounts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Println("\nWAF/CDN Detection:")
+	for _, name := range names {
+		fmt.Printf("  %s%-30s%s : %d\n", ColorAccent, name, ColorReset, wafCounts[name])
+	}
+	if wafBlockedCount > 0 {
+		fmt.Printf("  %s%-30s%s : %d (not counted as successful)\n", ColorWarning, "Blocked by WAF", ColorReset, wafBlockedCount)
+	}
+	if wafThrottledCount > 0 {
+		fmt.Printf("  %s%-30s%s : %d (host backed off)\n", ColorWarning, "Throttled", ColorReset, wafThrottledCount)
+	}
+}
+
+// describeWAF formats a WAF result for logs and terminal output
+func describeWAF(res *wafResult) string {
+	parts := []string{}
+	if res.waf != "" {
+		parts = append(parts, "waf="+res.waf)
+	}
+	if res.cdn != "" {
+		parts = append(parts, "cdn="+res.cdn)
+	}
+	if res.blocked {
+		parts = append(parts, "BLOCKED")
+	}
+	if res.throttled > 0 {
+		parts = append(parts, fmt.Sprintf("THROTTLED (backing off %s)", res.throttled.Round(time.Second)))
+	}
+	if len(res.evidence) > 0 {
+		parts = append(parts, "["+strings.Join(res.evidence, "; ")+"]")
+	}
+	return strings.Join(parts, " ")
+}